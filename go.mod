@@ -11,6 +11,8 @@ require (
 	github.com/kalafut/imohash v1.0.3
 	github.com/pelletier/go-toml v1.9.5
 	github.com/valkey-io/valkey-go v1.0.37
+	golang.org/x/crypto v0.22.0
+	golang.org/x/sys v0.19.0
 )
 
 require golang.org/x/sync v0.0.0-20210220032951-036812b2e83c // indirect
@@ -18,6 +20,5 @@ require golang.org/x/sync v0.0.0-20210220032951-036812b2e83c // indirect
 require (
 	github.com/huin/goupnp v1.3.0
 	github.com/twmb/murmur3 v1.1.5 // indirect
-	golang.org/x/sys v0.19.0 // indirect
 	lukechampine.com/uint128 v1.2.0 // indirect
 )
@@ -2,22 +2,44 @@ package main
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/altriusrs/netbeams/src/access"
+	"github.com/altriusrs/netbeams/src/admin"
+	"github.com/altriusrs/netbeams/src/bans"
+	"github.com/altriusrs/netbeams/src/chat"
 	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/crypto"
 	"github.com/altriusrs/netbeams/src/environment"
 	"github.com/altriusrs/netbeams/src/heartbeat"
 	"github.com/altriusrs/netbeams/src/http"
+	"github.com/altriusrs/netbeams/src/idle"
 	"github.com/altriusrs/netbeams/src/logs"
+	"github.com/altriusrs/netbeams/src/mods"
 	"github.com/altriusrs/netbeams/src/netcheck"
 	"github.com/altriusrs/netbeams/src/player_manager"
 	"github.com/altriusrs/netbeams/src/tcp"
 	"github.com/altriusrs/netbeams/src/types"
 	"github.com/altriusrs/netbeams/src/udp"
 	"github.com/altriusrs/netbeams/src/upnp"
+	"github.com/altriusrs/netbeams/src/vehicles"
 )
 
 func main() {
 
+	// Print a password hash for the config file, without starting the server
+	if len(os.Args) == 3 && os.Args[1] == "hash-password" {
+		hash, err := crypto.HashPassword(os.Args[2])
+
+		if err != nil {
+			fmt.Println("Unable to hash password: " + err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(hash)
+		return
+	}
+
 	environment.GetBuildContext()
 
 	// Spawn a new logger instance
@@ -62,6 +84,16 @@ func main() {
 		types.App.AddService(netchecker)
 	}
 
+	// Bans are loaded before anything else starts, so that players are never let in without their bans being checked
+	banService := bans.Service()
+	failed := banService.StartService()
+	if failed != nil {
+		logger.Error("Failed to load bans - Fix or remove the ban file to start the server")
+		logger.Error(failed.Error())
+		return
+	}
+	types.App.AddService(banService)
+
 	logger.Info("Starting server")
 	logger.Info("Name: " + config.Configuration.General.Name)
 	logger.Infof("Port: %d", config.Configuration.General.Port)
@@ -81,9 +113,15 @@ func main() {
 	types.App.AddService(configuration)
 	types.App.AddService(http.Service())
 	types.App.AddService(player_manager.Service())
+	types.App.AddService(access.Service())
+	types.App.AddService(mods.Service())
 	types.App.AddService(tcp.Service())
 	types.App.AddService(udp.Service())
+	types.App.AddService(chat.Service())
+	types.App.AddService(vehicles.Service())
+	types.App.AddService(idle.Service())
 	types.App.AddService(heartbeat.Service())
+	types.App.AddService(admin.Service())
 
 	switch mode {
 	case "main":
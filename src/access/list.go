@@ -0,0 +1,106 @@
+package access
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// A List of players, matched against their account name, account ID and identifiers (ignoring case)
+type List struct {
+	entries map[string]struct{}
+}
+
+// Create a list from its entries
+func NewList(entries ...[]string) *List {
+	l := &List{entries: make(map[string]struct{})}
+
+	for _, group := range entries {
+		for _, entry := range group {
+			if entry = normalize(entry); entry != "" {
+				l.entries[entry] = struct{}{}
+			}
+		}
+	}
+
+	return l
+}
+
+// ParseList reads the entries of a list, one per line.
+// Blank lines and lines starting with # are ignored.
+func ParseList(r io.Reader) ([]string, error) {
+	entries := []string{}
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entries = append(entries, line)
+	}
+
+	return entries, scanner.Err()
+}
+
+// ReadListFile reads the entries of a list from a file, returning no entries if the file does not exist
+func ReadListFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return ParseList(f)
+}
+
+// Len returns the number of entries in the list
+func (l *List) Len() int {
+	return len(l.entries)
+}
+
+// Contains reports whether an account is on the list
+func (l *List) Contains(account *types.Account) bool {
+	if account == nil {
+		return false
+	}
+
+	if l.has(account.Name) || l.has(account.Id) {
+		return true
+	}
+
+	for _, identifier := range account.Identifiers {
+		if l.has(identifier) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check for a single entry
+func (l *List) has(value string) bool {
+	if value = normalize(value); value == "" {
+		return false
+	}
+
+	_, ok := l.entries[value]
+
+	return ok
+}
+
+// Entries are compared ignoring case and surrounding whitespace
+func normalize(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
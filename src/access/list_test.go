@@ -0,0 +1,43 @@
+package access
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+func TestParseList(t *testing.T) {
+	entries, err := ParseList(strings.NewReader("# League drivers\nDriverOne\n\n  discord:1234  \n# 5678\n"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 || entries[0] != "DriverOne" || entries[1] != "discord:1234" {
+		t.Errorf("Unexpected entries: %v", entries)
+	}
+}
+
+func TestListContains(t *testing.T) {
+	list := NewList([]string{"DriverOne", "98765"}, []string{"Discord:1234"})
+
+	cases := []struct {
+		name     string
+		account  *types.Account
+		contains bool
+	}{
+		{"name", &types.Account{Name: "driverone", Id: "1"}, true},
+		{"id", &types.Account{Name: "DriverTwo", Id: "98765"}, true},
+		{"identifier", &types.Account{Name: "DriverThree", Id: "2", Identifiers: []string{"beammp:2", "discord:1234"}}, true},
+		{"missing", &types.Account{Name: "DriverFour", Id: "3", Identifiers: []string{"beammp:3"}}, false},
+		{"guest", &types.Account{Name: "guest123", Guest: true}, false},
+		{"nil", nil, false},
+	}
+
+	for _, c := range cases {
+		if contains := list.Contains(c.account); contains != c.contains {
+			t.Errorf("%s: expected contains to be %t, got %t", c.name, c.contains, contains)
+		}
+	}
+}
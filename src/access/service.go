@@ -0,0 +1,169 @@
+package access
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/types"
+	"github.com/fsnotify/fsnotify"
+)
+
+// An Access List service instance, which decides who may join the server using the allow and block lists.
+// The players in the config file are combined with those in the list files, which are reloaded when they change.
+type AccessService struct {
+	types.Service
+	mutex     sync.RWMutex
+	allowFile string            // The allow list file being watched
+	blockFile string            // The block list file being watched
+	allowed   []string          // The entries in the allow list file
+	blocked   []string          // The entries in the block list file
+	watcher   *fsnotify.Watcher // Watches the folders containing the list files
+}
+
+// Create a new Access List service instance
+func Service() *AccessService {
+	service := &AccessService{
+		Service: types.SpinUp("Access Lists"),
+	}
+
+	service.RegisterServiceHooks(service.Start, service.Stop, nil)
+
+	return service
+}
+
+// The list files are chosen when the service starts, so changing them in the config file requires a restart
+func (s *AccessService) Start() (types.Status, error) {
+	s.allowFile = cleanPath(config.Configuration.Auth.AllowList.File)
+	s.blockFile = cleanPath(config.Configuration.Auth.BlockList.File)
+
+	s.reload(s.allowFile)
+	s.reload(s.blockFile)
+
+	watcher, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		s.Error("Unable to watch the access list files - Additional output below")
+		return types.StatusErrored, err
+	}
+
+	// The folders are watched rather than the files, as editors often replace a file when saving it
+	folders := map[string]bool{}
+
+	for _, path := range []string{s.allowFile, s.blockFile} {
+		if path == "" || folders[filepath.Dir(path)] {
+			continue
+		}
+
+		folders[filepath.Dir(path)] = true
+
+		if err = watcher.Add(filepath.Dir(path)); err != nil {
+			s.Error("Unable to watch the access list files - Additional output below")
+			s.Error(err.Error())
+		}
+	}
+
+	s.watcher = watcher
+
+	go s.Watch()
+
+	return types.StatusHealthy, nil
+}
+
+func (s *AccessService) Stop() (types.Status, error) {
+	if s.watcher != nil {
+		_ = s.watcher.Close()
+	}
+
+	return types.StatusShutdown, nil
+}
+
+// Watch the list files, reloading them whenever they change
+func (s *AccessService) Watch() {
+	for {
+		select {
+		case event, ok := <-s.watcher.Events:
+			if !ok {
+				return
+			}
+
+			if path := filepath.Clean(event.Name); path == s.allowFile || path == s.blockFile {
+				s.Debugf("Access list %s changed (%s)", event.Name, event.Op)
+				s.reload(path)
+			}
+		case err, ok := <-s.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			s.Error(err.Error())
+		}
+	}
+}
+
+// Reload one of the list files, keeping the previous entries if it cannot be read
+func (s *AccessService) reload(path string) {
+	if path == "" {
+		return
+	}
+
+	entries, err := ReadListFile(path)
+
+	if err != nil {
+		s.Errorf("Error reading access list %s - Additional output below", path)
+		s.Error(err.Error())
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if path == s.allowFile {
+		s.allowed = entries
+	}
+
+	if path == s.blockFile {
+		s.blocked = entries
+	}
+
+	s.Infof("Loaded %d players from %s", len(entries), path)
+}
+
+// AllowList returns the players on the allow list
+func (s *AccessService) AllowList() *List {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return NewList(config.Configuration.Auth.AllowList.Players, s.allowed)
+}
+
+// BlockList returns the players on the block list
+func (s *AccessService) BlockList() *List {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return NewList(config.Configuration.Auth.BlockList.Players, s.blocked)
+}
+
+// Allowed reports whether an account may join the server, which is always the case unless the allow list is enabled
+func (s *AccessService) Allowed(account *types.Account) bool {
+	if !config.Configuration.Auth.AllowList.Enable {
+		return true
+	}
+
+	return s.AllowList().Contains(account)
+}
+
+// Blocked reports whether an account is on the block list
+func (s *AccessService) Blocked(account *types.Account) bool {
+	return s.BlockList().Contains(account)
+}
+
+// Clean a configured path, leaving it empty if it is not set
+func cleanPath(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	return filepath.Clean(path)
+}
@@ -0,0 +1,188 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/bans"
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// A request to ban a player through the admin API
+type BanRequest struct {
+	AccountId  string `json:"account_id"`
+	Identifier string `json:"identifier"`
+	Network    string `json:"network"`
+	Name       string `json:"name"`
+	Reason     string `json:"reason"`
+	Issuer     string `json:"issuer"`
+	Duration   string `json:"duration"` // How long the ban lasts (eg. 2h or 3 days), or empty for a permanent ban
+}
+
+// A request to kick a player through the admin API
+type KickRequest struct {
+	Id     int    `json:"id"`
+	Reason string `json:"reason"`
+	Issuer string `json:"issuer"`
+}
+
+// Get the ban service, which is looked up on each request as services start in no particular order
+func banService() *bans.BanService {
+	bs, ok := types.App.GetService("Bans").(*bans.BanService)
+
+	if !ok {
+		return nil
+	}
+
+	return bs
+}
+
+// HandleBans lists bans (GET), adds a ban (POST), or removes the ban given by the id parameter (DELETE)
+func (s *AdminService) HandleBans(w http.ResponseWriter, r *http.Request) {
+	bs := banService()
+
+	if bs == nil {
+		http.Error(w, "Ban service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := bs.List()
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		s.writeJSON(w, http.StatusOK, list)
+	case http.MethodPost:
+		var request BanRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid ban - "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ban := bans.Ban{
+			AccountId:  request.AccountId,
+			Identifier: request.Identifier,
+			Network:    request.Network,
+			Name:       request.Name,
+			Reason:     request.Reason,
+			Issuer:     request.Issuer,
+		}
+
+		if request.Duration != "" {
+			duration, err := config.ParseDuration(request.Duration, time.Second)
+
+			if err != nil || duration <= 0 {
+				http.Error(w, "Invalid ban duration", http.StatusBadRequest)
+				return
+			}
+
+			ban.ExpiresAt = time.Now().Add(duration)
+		}
+
+		if !ban.Valid() {
+			http.Error(w, "A ban needs a valid account ID, identifier or network", http.StatusBadRequest)
+			return
+		}
+
+		ban, err := bs.Add(ban)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		s.kickBanned(ban)
+		s.writeJSON(w, http.StatusCreated, ban)
+	case http.MethodDelete:
+		err := bs.Remove(r.URL.Query().Get("id"))
+
+		if errors.Is(err, bans.ErrBanNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleKick kicks a player, who is then banned for the configured admin kick duration
+func (s *AdminService) HandleKick(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	server := tcpServer()
+
+	if server == nil {
+		http.Error(w, "TCP server unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var request KickRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid kick - "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := server.KickPlayer(request.Id, request.Reason, request.Issuer); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Disconnect players who are already on the server when a ban which applies to them is added
+func (s *AdminService) kickBanned(ban bans.Ban) {
+	server := tcpServer()
+
+	if server == nil {
+		return
+	}
+
+	now := time.Now()
+
+	for _, c := range server.Connections.Snapshot() {
+		player := c.GetPlayer()
+
+		if player.Account == nil {
+			continue
+		}
+
+		subject := bans.Subject{
+			AccountId:   player.Account.Id,
+			Identifiers: player.Account.Identifiers,
+			Address:     c.RemoteIP(),
+		}
+
+		if ban.Matches(subject) {
+			s.Infof("Disconnecting %s - Banned by %s", player.DisplayName, ban.Issuer)
+			c.Disconnect(ban.Message(now))
+		}
+	}
+}
+
+// Send a value as JSON
+func (s *AdminService) writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		s.Debugf("Unable to send response - %s", err.Error())
+	}
+}
@@ -0,0 +1,84 @@
+package admin
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/altriusrs/netbeams/src/tcp"
+)
+
+// A snapshot of the server metrics
+type Metrics struct {
+	Connections int               // The number of open TCP connections
+	Players     []PlayerInfo      // The players on the server
+	Downloads   tcp.DownloadStats // The mod download throughput
+	Unknown     map[byte]uint64   // The number of messages received for each unknown code
+}
+
+// Encode the metrics in the Prometheus text format
+func (m *Metrics) Encode() string {
+	b := &strings.Builder{}
+
+	header(b, "netbeams_connections", "gauge", "The number of open TCP connections")
+	fmt.Fprintf(b, "netbeams_connections %d\n", m.Connections)
+
+	header(b, "netbeams_players", "gauge", "The number of players on the server")
+	fmt.Fprintf(b, "netbeams_players %d\n", len(m.Players))
+
+	header(b, "netbeams_player_rtt_seconds", "gauge", "The smoothed round trip time of each player")
+	for _, p := range m.Players {
+		if p.Samples > 0 {
+			fmt.Fprintf(b, "netbeams_player_rtt_seconds{id=\"%d\",name=%s} %s\n", p.Id, label(p.Name), seconds(p.RTT))
+		}
+	}
+
+	header(b, "netbeams_player_jitter_seconds", "gauge", "The smoothed round trip time variation of each player")
+	for _, p := range m.Players {
+		if p.Samples > 0 {
+			fmt.Fprintf(b, "netbeams_player_jitter_seconds{id=\"%d\",name=%s} %s\n", p.Id, label(p.Name), seconds(p.Jitter))
+		}
+	}
+
+	header(b, "netbeams_mod_download_bytes_per_second", "gauge", "The current mod download rate across all clients")
+	fmt.Fprintf(b, "netbeams_mod_download_bytes_per_second %d\n", m.Downloads.Rate)
+
+	header(b, "netbeams_mod_download_bytes_total", "counter", "The number of bytes of mod data sent across all clients")
+	fmt.Fprintf(b, "netbeams_mod_download_bytes_total %d\n", m.Downloads.Total)
+
+	header(b, "netbeams_mod_downloads", "gauge", "The number of clients currently downloading mods")
+	fmt.Fprintf(b, "netbeams_mod_downloads %d\n", len(m.Downloads.Throughput))
+
+	codes := make([]int, 0, len(m.Unknown))
+	for code := range m.Unknown {
+		codes = append(codes, int(code))
+	}
+	sort.Ints(codes)
+
+	header(b, "netbeams_unknown_messages_total", "counter", "The number of messages received with an unknown code")
+	for _, code := range codes {
+		fmt.Fprintf(b, "netbeams_unknown_messages_total{code=%s} %d\n", label(string(rune(code))), m.Unknown[byte(code)])
+	}
+
+	return b.String()
+}
+
+// Write the help and type lines for a metric
+func header(b *strings.Builder, name string, kind string, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// Quote a label value, escaping it as required by the Prometheus text format
+func label(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+
+	return `"` + value + `"`
+}
+
+// Convert fractional milliseconds to a number of seconds
+func seconds(ms float64) string {
+	return strconv.FormatFloat(ms/1000, 'f', -1, 64)
+}
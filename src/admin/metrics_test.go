@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/altriusrs/netbeams/src/tcp"
+)
+
+func TestMetricsEncode(t *testing.T) {
+	metrics := Metrics{
+		Connections: 3,
+		Players: []PlayerInfo{
+			{Id: 0, Name: `Driver "One"`, RTT: 85, Jitter: 4.5, Samples: 10},
+			{Id: 1, Name: "Loading", Samples: 0},
+		},
+		Downloads: tcp.DownloadStats{Rate: 2048, Total: 1 << 20},
+		Unknown:   map[byte]uint64{'X': 2},
+	}
+
+	encoded := metrics.Encode()
+
+	expected := []string{
+		"netbeams_connections 3\n",
+		"netbeams_players 2\n",
+		"netbeams_player_rtt_seconds{id=\"0\",name=\"Driver \\\"One\\\"\"} 0.085\n",
+		"netbeams_player_jitter_seconds{id=\"0\",name=\"Driver \\\"One\\\"\"} 0.0045\n",
+		"netbeams_mod_download_bytes_per_second 2048\n",
+		"netbeams_mod_download_bytes_total 1048576\n",
+		"netbeams_unknown_messages_total{code=\"X\"} 2\n",
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(encoded, line) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", line, encoded)
+		}
+	}
+
+	if strings.Contains(encoded, `id="1"`) {
+		t.Errorf("Expected players without latency samples to be skipped, got:\n%s", encoded)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	if got := label("a\\b\n\"c\""); got != `"a\\b\n\"c\""` {
+		t.Errorf("Unexpected label escaping: %s", got)
+	}
+}
@@ -0,0 +1,253 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/crypto"
+	"github.com/altriusrs/netbeams/src/tcp"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// How long the admin API waits for open requests when the server shuts down
+const ShutdownTimeout = 5 * time.Second
+
+// An Admin API service instance, which serves the player list and metrics to server operators
+type AdminService struct {
+	types.Service
+	server *http.Server // The HTTP server, or nil when the admin API is disabled
+}
+
+// A player as shown by the admin API
+type PlayerInfo struct {
+	Id         int          `json:"id"`
+	Name       string       `json:"name"`
+	AccountId  string       `json:"account_id"`
+	Guest      bool         `json:"guest"`
+	Roles      []types.Role `json:"roles"`
+	State      string       `json:"state"`
+	Address    string       `json:"address"`
+	UDPAddress string       `json:"udp_address,omitempty"`
+	Vehicles   int          `json:"vehicles"`
+	RTT        float64      `json:"rtt_ms"`
+	Jitter     float64      `json:"jitter_ms"`
+	Samples    int          `json:"latency_samples"`
+}
+
+// Create a new Admin API service instance
+func Service() *AdminService {
+	service := &AdminService{
+		Service: types.SpinUp("Admin API"),
+	}
+
+	service.RegisterServiceHooks(service.Start, service.Stop, nil)
+
+	return service
+}
+
+func (s *AdminService) Start() (types.Status, error) {
+	address := config.Configuration.NetBeams.AdminAddress
+
+	if address == "" {
+		s.Info("Admin API disabled")
+		return types.StatusHealthy, nil
+	}
+
+	listener, err := net.Listen("tcp", address)
+
+	if err != nil {
+		s.Error("Error starting admin API - Additional output below")
+		s.Error(err.Error())
+		return types.StatusErrored, err
+	}
+
+	s.server = &http.Server{
+		Handler:           s.Routes(config.Configuration.NetBeams.AdminToken),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.Error("Admin API stopped - Additional output below")
+			s.Error(err.Error())
+		}
+	}()
+
+	s.Infof("Admin API listening on %s", address)
+
+	return types.StatusHealthy, nil
+}
+
+// Routes creates the handler for the admin API.
+// The player list shows the address of each player, and bans and kicks change the server,
+// so they are only served when an admin token is set, and only to callers presenting it.
+// Metrics are left open for scrapers, as they only carry player names and timings.
+func (s *AdminService) Routes(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.HandleMetrics)
+
+	if token == "" {
+		s.Warn("No admin token set - Only metrics are served by the admin API")
+		return mux
+	}
+
+	mux.HandleFunc("/players", Authorize(token, s.HandlePlayers))
+	mux.HandleFunc("/bans", Authorize(token, s.HandleBans))
+	mux.HandleFunc("/kick", Authorize(token, s.HandleKick))
+
+	return mux
+}
+
+// Authorize wraps a handler so that it is only served to requests carrying the admin token as a bearer token
+func Authorize(token string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		given, ok := bearerToken(r)
+
+		// The token is compared in constant time, and may be stored as a bcrypt hash
+		if !ok || !crypto.ComparePassword(given, token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="NetBeams"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+// Get the bearer token from the Authorization header of a request
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+
+	return token, true
+}
+
+func (s *AdminService) Stop() (types.Status, error) {
+	if s.server == nil {
+		return types.StatusShutdown, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		return types.StatusErrored, err
+	}
+
+	return types.StatusShutdown, nil
+}
+
+// Get the TCP server, which is looked up on each request as services start in no particular order
+func tcpServer() *tcp.Server {
+	server, ok := types.App.GetService("TCP Server").(*tcp.Server)
+
+	if !ok {
+		return nil
+	}
+
+	return server
+}
+
+// Players returns the players which have authenticated with the server, ordered by player ID
+func Players(server *tcp.Server) []PlayerInfo {
+	players := []PlayerInfo{}
+
+	for _, c := range server.Connections.Snapshot() {
+		player := c.GetPlayer()
+
+		if player.Account == nil {
+			continue
+		}
+
+		info := PlayerInfo{
+			Id:        player.PlayerId,
+			Name:      player.DisplayName,
+			AccountId: player.Account.Id,
+			Guest:     player.Account.Guest,
+			Roles:     player.Account.Roles,
+			State:     c.GetState().String(),
+			Address:   c.Address,
+			Vehicles:  len(player.Vehicles),
+			RTT:       milliseconds(player.Latency.RTT),
+			Jitter:    milliseconds(player.Latency.Jitter),
+			Samples:   player.Latency.Samples,
+		}
+
+		if player.UDPAddress != nil {
+			info.UDPAddress = player.UDPAddress.String()
+		}
+
+		players = append(players, info)
+	}
+
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].Id < players[j].Id
+	})
+
+	return players
+}
+
+// HandlePlayers lists the players on the server as JSON
+func (s *AdminService) HandlePlayers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	server := tcpServer()
+
+	if server == nil {
+		http.Error(w, "TCP server unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(Players(server)); err != nil {
+		s.Debugf("Unable to send player list - %s", err.Error())
+	}
+}
+
+// HandleMetrics exposes the server metrics in the Prometheus text format
+func (s *AdminService) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	server := tcpServer()
+
+	if server == nil {
+		http.Error(w, "TCP server unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	metrics := Metrics{
+		Connections: len(server.Connections.Snapshot()),
+		Players:     Players(server),
+		Downloads:   server.DownloadStats(),
+		Unknown:     server.Dispatcher.UnknownCounts(),
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	if _, err := fmt.Fprint(w, metrics.Encode()); err != nil {
+		s.Debugf("Unable to send metrics - %s", err.Error())
+	}
+}
+
+// Convert a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/altriusrs/netbeams/src/crypto"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// Send a request to the admin API, returning the status code
+func request(t *testing.T, handler http.Handler, method string, path string, token string) int {
+	t.Helper()
+
+	r := httptest.NewRequest(method, path, nil)
+
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	return w.Code
+}
+
+func TestRoutesWithoutToken(t *testing.T) {
+	types.NewApplication()
+	routes := Service().Routes("")
+
+	// Without a token, the player list, bans and kicks cannot be reached at all
+	for _, test := range []struct{ method, path string }{
+		{http.MethodGet, "/players"},
+		{http.MethodGet, "/bans"},
+		{http.MethodPost, "/bans"},
+		{http.MethodDelete, "/bans?id=1"},
+		{http.MethodPost, "/kick"},
+	} {
+		if code := request(t, routes, test.method, test.path, "anything"); code != http.StatusNotFound {
+			t.Errorf("%s %s: expected %d, got %d", test.method, test.path, http.StatusNotFound, code)
+		}
+	}
+
+	// Metrics are left open for scrapers
+	if code := request(t, routes, http.MethodGet, "/metrics", ""); code == http.StatusNotFound || code == http.StatusUnauthorized {
+		t.Errorf("GET /metrics: expected to be served, got %d", code)
+	}
+}
+
+func TestRoutesRequireToken(t *testing.T) {
+	types.NewApplication()
+
+	hash, err := crypto.HashPassword("hashed-secret")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		configured string // The admin token set in the config
+		given      string // The bearer token sent with the request
+		want       int
+	}{
+		{"missing token", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "guess", http.StatusUnauthorized},
+		// The services behind the endpoints are not running, so authorized requests reach the handler and fail there
+		{"plain text token", "secret", "secret", http.StatusServiceUnavailable},
+		{"hashed token", hash, "hashed-secret", http.StatusServiceUnavailable},
+	}
+
+	for _, test := range tests {
+		routes := Service().Routes(test.configured)
+
+		for _, endpoint := range []struct{ method, path string }{
+			{http.MethodGet, "/players"},
+			{http.MethodPost, "/bans"},
+			{http.MethodPost, "/kick"},
+		} {
+			if code := request(t, routes, endpoint.method, endpoint.path, test.given); code != test.want {
+				t.Errorf("%s: %s %s expected %d, got %d", test.name, endpoint.method, endpoint.path, test.want, code)
+			}
+		}
+
+		// Metrics do not need the token
+		if code := request(t, routes, http.MethodGet, "/metrics", ""); code == http.StatusUnauthorized {
+			t.Errorf("%s: GET /metrics expected to be served without a token", test.name)
+		}
+	}
+}
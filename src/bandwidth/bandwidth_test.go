@@ -0,0 +1,73 @@
+package bandwidth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketReserve(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewBucket(1000)
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	if wait := b.Reserve(1000); wait != 0 {
+		t.Errorf("Expected a full bucket to allow a burst, waited %s", wait)
+	}
+
+	if wait := b.Reserve(500); wait != 500*time.Millisecond {
+		t.Errorf("Expected to wait 500ms, waited %s", wait)
+	}
+
+	now = now.Add(time.Second)
+
+	if wait := b.Reserve(500); wait != 0 {
+		t.Errorf("Expected the debt to be repaid after a second, waited %s", wait)
+	}
+
+	now = now.Add(time.Hour)
+
+	if wait := b.Reserve(1500); wait != 500*time.Millisecond {
+		t.Errorf("Expected the bucket to be capped at its burst, waited %s", wait)
+	}
+}
+
+func TestNilBucket(t *testing.T) {
+	var b *Bucket = NewBucket(0)
+
+	if b != nil {
+		t.Fatal("Expected a bucket without a rate to be nil")
+	}
+
+	if wait := b.Reserve(1 << 30); wait != 0 {
+		t.Errorf("Expected a nil bucket not to limit, waited %s", wait)
+	}
+
+	if !Wait(1<<30, nil, b) {
+		t.Error("Expected a nil bucket not to block")
+	}
+}
+
+func TestMeter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	m := NewMeter()
+	m.now = func() time.Time { return now }
+
+	m.Add(5000)
+	now = now.Add(time.Second)
+	m.Add(5000)
+
+	if rate := m.Rate(); rate != 2000 {
+		t.Errorf("Expected a rate of 2000, got %d", rate)
+	}
+
+	now = now.Add(MeterWindow * time.Second)
+
+	if rate := m.Rate(); rate != 0 {
+		t.Errorf("Expected the rate to drop to 0, got %d", rate)
+	}
+
+	if total := m.Total(); total != 10000 {
+		t.Errorf("Expected a total of 10000, got %d", total)
+	}
+}
@@ -0,0 +1,99 @@
+package bandwidth
+
+import (
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket which limits the rate data is sent at.
+// A nil bucket does not limit anything.
+type Bucket struct {
+	mutex  sync.Mutex
+	rate   float64          // The number of bytes added to the bucket each second
+	burst  float64          // The maximum number of bytes the bucket can hold
+	tokens float64          // The number of bytes which can be sent without waiting (negative when in debt)
+	last   time.Time        // When the bucket was last refilled
+	now    func() time.Time // The clock used by the bucket
+}
+
+// Create a new bucket which allows rate bytes per second, with bursts of up to one second of data.
+// Returns nil (no limit) if the rate is not positive.
+func NewBucket(rate int64) *Bucket {
+	if rate <= 0 {
+		return nil
+	}
+
+	b := &Bucket{
+		rate:  float64(rate),
+		burst: float64(rate),
+		now:   time.Now,
+	}
+
+	b.tokens = b.burst
+	b.last = b.now()
+
+	return b
+}
+
+// Rate returns the number of bytes per second allowed by the bucket, or 0 if it does not limit anything
+func (b *Bucket) Rate() int64 {
+	if b == nil {
+		return 0
+	}
+
+	return int64(b.rate)
+}
+
+// Reserve takes n bytes from the bucket, returning how long the caller must wait before sending them.
+// Sends larger than the bucket put it into debt, which later senders wait out.
+func (b *Bucket) Reserve(n int) time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.now()
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	b.last = now
+
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+
+	b.tokens -= float64(n)
+
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Wait takes n bytes from every bucket, sleeping until they may be sent.
+// Returns false if done is closed while waiting.
+func Wait(n int, done <-chan struct{}, buckets ...*Bucket) bool {
+	var delay time.Duration
+
+	for _, b := range buckets {
+		if wait := b.Reserve(n); wait > delay {
+			delay = wait
+		}
+	}
+
+	if delay <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-done:
+		return false
+	}
+}
@@ -0,0 +1,71 @@
+package bandwidth
+
+import (
+	"sync"
+	"time"
+)
+
+// The number of seconds the throughput of a meter is averaged over
+const MeterWindow = 5
+
+// Meter measures the throughput of data sent
+type Meter struct {
+	mutex   sync.Mutex
+	total   int64              // The total number of bytes recorded
+	buckets [MeterWindow]int64 // The bytes recorded in each of the last few seconds
+	second  int64              // The Unix second of the newest bucket
+	now     func() time.Time   // The clock used by the meter
+}
+
+// Create a new meter
+func NewMeter() *Meter {
+	return &Meter{now: time.Now}
+}
+
+// Add records n bytes as sent
+func (m *Meter) Add(n int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.advance()
+	m.buckets[m.second%MeterWindow] += n
+	m.total += n
+}
+
+// Rate returns the average number of bytes sent per second over the meter window
+func (m *Meter) Rate() int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.advance()
+
+	var sum int64
+	for _, n := range m.buckets {
+		sum += n
+	}
+
+	return sum / MeterWindow
+}
+
+// Total returns the number of bytes recorded since the meter was created
+func (m *Meter) Total() int64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.total
+}
+
+// Clear the buckets for any seconds which have passed since the meter was last used
+func (m *Meter) advance() {
+	second := m.now().Unix()
+
+	if second-m.second >= MeterWindow {
+		m.buckets = [MeterWindow]int64{}
+	} else {
+		for s := m.second + 1; s <= second; s++ {
+			m.buckets[s%MeterWindow] = 0
+		}
+	}
+
+	m.second = second
+}
@@ -0,0 +1,123 @@
+package bans
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// A Ban prevents matching players from joining the server.
+// A ban matches a player if any of its account ID, identifier or network match.
+type Ban struct {
+	Id         string    `json:"id"`                   // The unique ID of the ban
+	AccountId  string    `json:"account_id,omitempty"` // The BeamMP account ID which is banned
+	Identifier string    `json:"identifier,omitempty"` // An account identifier which is banned (eg. discord:1234)
+	Network    string    `json:"network,omitempty"`    // An IP address or CIDR range which is banned
+	Name       string    `json:"name,omitempty"`       // The name of the player when they were banned, for reference
+	Reason     string    `json:"reason"`               // Why the player was banned
+	Issuer     string    `json:"issuer"`               // Who banned the player
+	CreatedAt  time.Time `json:"created_at"`           // When the ban was created
+	ExpiresAt  time.Time `json:"expires_at"`           // When the ban ends (zero for a permanent ban)
+}
+
+// The details of a player which are checked against bans
+type Subject struct {
+	AccountId   string     // The BeamMP account ID of the player
+	Identifiers []string   // The identifiers of the account
+	Address     netip.Addr // The IP address the player is connecting from
+}
+
+// Generate a new, random ban ID
+func NewId() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Permanent reports whether the ban never expires
+func (b *Ban) Permanent() bool {
+	return b.ExpiresAt.IsZero()
+}
+
+// Expired reports whether a temporary ban has ended
+func (b *Ban) Expired(now time.Time) bool {
+	return !b.Permanent() && !now.Before(b.ExpiresAt)
+}
+
+// Remaining returns how much longer a temporary ban lasts
+func (b *Ban) Remaining(now time.Time) time.Duration {
+	if b.Permanent() {
+		return 0
+	}
+
+	return b.ExpiresAt.Sub(now)
+}
+
+// Valid reports whether the ban has something to match players against
+func (b *Ban) Valid() bool {
+	if b.Network != "" {
+		if _, err := parseNetwork(b.Network); err != nil {
+			return false
+		}
+	}
+
+	return b.AccountId != "" || b.Identifier != "" || b.Network != ""
+}
+
+// Matches reports whether the ban applies to a player
+func (b *Ban) Matches(subject Subject) bool {
+	if b.AccountId != "" && b.AccountId == subject.AccountId {
+		return true
+	}
+
+	if b.Identifier != "" {
+		for _, identifier := range subject.Identifiers {
+			if strings.EqualFold(b.Identifier, identifier) {
+				return true
+			}
+		}
+	}
+
+	if b.Network != "" && subject.Address.IsValid() {
+		if network, err := parseNetwork(b.Network); err == nil && network.Contains(subject.Address.Unmap()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Message describes the ban to the player it applies to
+func (b *Ban) Message(now time.Time) string {
+	message := "You are banned from this server"
+
+	if b.Reason != "" {
+		message += " - " + b.Reason
+	}
+
+	if !b.Permanent() {
+		message += " - You may rejoin in " + b.Remaining(now).Round(time.Second).String()
+	}
+
+	return message
+}
+
+// Parse a single IP address or a CIDR range
+func parseNetwork(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		return prefix.Masked(), err
+	}
+
+	addr, err := netip.ParseAddr(value)
+
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	addr = addr.Unmap()
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
@@ -0,0 +1,143 @@
+package bans
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+func TestBanMatches(t *testing.T) {
+	subject := Subject{
+		AccountId:   "1234",
+		Identifiers: []string{"beammp:1234", "discord:5678"},
+		Address:     netip.MustParseAddr("::ffff:192.0.2.10"),
+	}
+
+	cases := []struct {
+		name    string
+		ban     Ban
+		matches bool
+	}{
+		{"account", Ban{AccountId: "1234"}, true},
+		{"other account", Ban{AccountId: "4321"}, false},
+		{"identifier", Ban{Identifier: "Discord:5678"}, true},
+		{"other identifier", Ban{Identifier: "discord:1234"}, false},
+		{"address", Ban{Network: "192.0.2.10"}, true},
+		{"network", Ban{Network: "192.0.2.0/24"}, true},
+		{"other network", Ban{Network: "198.51.100.0/24"}, false},
+		{"invalid network", Ban{Network: "not an address"}, false},
+	}
+
+	for _, c := range cases {
+		if matches := c.ban.Matches(subject); matches != c.matches {
+			t.Errorf("%s: expected match to be %t, got %t", c.name, c.matches, matches)
+		}
+	}
+}
+
+func TestBanExpiry(t *testing.T) {
+	now := time.Now()
+	ban := Ban{AccountId: "1234", Reason: "Ramming", ExpiresAt: now.Add(time.Hour)}
+
+	if ban.Expired(now) {
+		t.Error("Expected the ban to be active")
+	}
+
+	if !ban.Expired(now.Add(time.Hour)) {
+		t.Error("Expected the ban to have expired")
+	}
+
+	if message := ban.Message(now); message != "You are banned from this server - Ramming - You may rejoin in 1h0m0s" {
+		t.Errorf("Unexpected ban message: %s", message)
+	}
+
+	permanent := Ban{AccountId: "1234"}
+
+	if permanent.Expired(now.Add(24 * 365 * time.Hour)) {
+		t.Error("Expected a permanent ban to never expire")
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bans.json")
+	now := time.Now()
+
+	store, err := OpenFileStore(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = store.Add(Ban{Id: "permanent", AccountId: "1234", CreatedAt: now})
+	_ = store.Add(Ban{Id: "temporary", Network: "192.0.2.0/24", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	_ = store.Add(Ban{Id: "expired", AccountId: "5678", CreatedAt: now, ExpiresAt: now.Add(-time.Hour)})
+
+	if err = store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := OpenFileStore(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := loaded.List()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 active bans to be loaded, got %d", len(list))
+	}
+
+	ban, err := Find(loaded, Subject{Address: netip.MustParseAddr("192.0.2.99")}, now)
+
+	if err != nil || ban == nil || ban.Id != "temporary" {
+		t.Errorf("Expected the network ban to apply, got %v (%v)", ban, err)
+	}
+
+	if ban, _ = Find(loaded, Subject{AccountId: "5678"}, now); ban != nil {
+		t.Errorf("Expected the expired ban not to apply, got %s", ban.Id)
+	}
+
+	if err = loaded.Remove("permanent"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = loaded.Remove("permanent"); err != ErrBanNotFound {
+		t.Errorf("Expected removing a missing ban to fail, got %v", err)
+	}
+}
+
+func TestServiceRefusesUnreadableBanFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bans.json")
+
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config.Configuration.NetBeams.BanStore = "file"
+	config.Configuration.NetBeams.BanFile = path
+
+	s := Service()
+
+	// The server does not start when this fails, rather than letting players in without checking their bans
+	if err := s.StartService(); err == nil {
+		t.Fatal("Expected the service not to start with an unreadable ban file")
+	}
+
+	if status := s.GetStatus(); status == nil || *status != types.StatusErrored {
+		t.Errorf("Expected the service to have errored, got %v", status)
+	}
+
+	if _, err := s.Check(Subject{AccountId: "1234"}); err == nil {
+		t.Error("Expected checking a ban without a store to fail")
+	}
+}
@@ -0,0 +1,133 @@
+package bans
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileStore keeps bans in a JSON file
+type FileStore struct {
+	mutex sync.Mutex
+	path  string         // The file the bans are saved to
+	bans  map[string]Ban // The bans, keyed by ID
+}
+
+// OpenFileStore reads the bans saved in a file, starting empty if the file does not exist yet
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		path: path,
+		bans: make(map[string]Ban),
+	}
+
+	content, err := os.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	list := []Ban{}
+
+	if err = json.Unmarshal(content, &list); err != nil {
+		return nil, err
+	}
+
+	for _, ban := range list {
+		s.bans[ban.Id] = ban
+	}
+
+	return s, nil
+}
+
+func (s *FileStore) Add(ban Ban) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.bans[ban.Id] = ban
+
+	return s.save()
+}
+
+func (s *FileStore) Remove(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.bans[id]; !ok {
+		return ErrBanNotFound
+	}
+
+	delete(s.bans, id)
+
+	return s.save()
+}
+
+func (s *FileStore) List() ([]Ban, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.active(time.Now()), nil
+}
+
+func (s *FileStore) Close() error {
+	return nil
+}
+
+// Get the bans which have not expired, oldest first (the caller must hold the lock)
+func (s *FileStore) active(now time.Time) []Ban {
+	list := make([]Ban, 0, len(s.bans))
+
+	for _, ban := range s.bans {
+		if !ban.Expired(now) {
+			list = append(list, ban)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+
+	return list
+}
+
+// Write the bans which have not expired to the file, replacing it in one step (the caller must hold the lock)
+func (s *FileStore) save() error {
+	now := time.Now()
+
+	for id, ban := range s.bans {
+		if ban.Expired(now) {
+			delete(s.bans, id)
+		}
+	}
+
+	content, err := json.MarshalIndent(s.active(now), "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
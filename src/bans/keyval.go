@@ -0,0 +1,89 @@
+package bans
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/keyval"
+)
+
+// The prefix of the keys bans are stored under
+const KeyvalPrefix = "netbeams:bans:"
+
+// KeyvalStore keeps bans in valkey, so that they can be shared between servers.
+// Temporary bans are stored with an expiry, so valkey removes them once they end.
+type KeyvalStore struct {
+	client *keyval.KeyvalClient
+}
+
+// Create a ban store using a valkey client
+func NewKeyvalStore(client *keyval.KeyvalClient) *KeyvalStore {
+	return &KeyvalStore{client: client}
+}
+
+func (s *KeyvalStore) Add(ban Ban) error {
+	content, err := json.Marshal(ban)
+
+	if err != nil {
+		return err
+	}
+
+	if ban.Permanent() {
+		return s.client.Set(KeyvalPrefix+ban.Id, string(content)).Error()
+	}
+
+	return s.client.SetUntil(KeyvalPrefix+ban.Id, string(content), ban.ExpiresAt).Error()
+}
+
+func (s *KeyvalStore) Remove(id string) error {
+	removed, err := s.client.Del(KeyvalPrefix + id).AsInt64()
+
+	if err != nil {
+		return err
+	}
+
+	if removed == 0 {
+		return ErrBanNotFound
+	}
+
+	return nil
+}
+
+func (s *KeyvalStore) List() ([]Ban, error) {
+	keys, err := s.client.Scan(KeyvalPrefix + "*")
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	list := []Ban{}
+
+	for _, key := range keys {
+		content, err := s.client.Get(key).ToString()
+
+		// The ban may have expired since the scan
+		if keyval.IsNil(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		var ban Ban
+
+		if err = json.Unmarshal([]byte(content), &ban); err != nil {
+			return nil, err
+		}
+
+		if !ban.Expired(now) {
+			list = append(list, ban)
+		}
+	}
+
+	return list, nil
+}
+
+func (s *KeyvalStore) Close() error {
+	s.client.Close()
+	return nil
+}
@@ -0,0 +1,191 @@
+package bans
+
+import (
+	"fmt"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/keyval"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// A Ban service instance, which keeps the bans for the server
+type BanService struct {
+	types.Service
+	mutex sync.RWMutex // Guards the store
+	store Store        // Where the bans are kept (nil until the service starts)
+}
+
+// Create a new Ban service instance
+func Service() *BanService {
+	service := &BanService{
+		Service: types.SpinUp("Bans"),
+	}
+
+	service.RegisterServiceHooks(service.Start, service.Stop, nil)
+
+	return service
+}
+
+func (s *BanService) Start() (types.Status, error) {
+	settings := config.Configuration.NetBeams
+	var store Store
+
+	if settings.BanStore == "keyval" {
+		client, err := keyval.NewKeyvalClient()
+
+		if err != nil {
+			s.Error("Error connecting to valkey - Falling back to the ban file - Additional output below")
+			s.Error(err.Error())
+		} else {
+			s.Info("Using valkey for bans")
+			store = NewKeyvalStore(client)
+		}
+	}
+
+	if store == nil {
+		file, err := OpenFileStore(settings.BanFile)
+
+		if err != nil {
+			s.Error("Error loading bans - Additional output below")
+			s.Error(err.Error())
+			return types.StatusErrored, err
+		}
+
+		s.Infof("Using %s for bans", settings.BanFile)
+		store = file
+	}
+
+	s.mutex.Lock()
+	s.store = store
+	s.mutex.Unlock()
+
+	return types.StatusHealthy, nil
+}
+
+func (s *BanService) Stop() (types.Status, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.store != nil {
+		if err := s.store.Close(); err != nil {
+			return types.StatusErrored, err
+		}
+	}
+
+	return types.StatusShutdown, nil
+}
+
+// Get the store, or an error if the service has not started
+func (s *BanService) getStore() (Store, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.store == nil {
+		return nil, fmt.Errorf("the ban store is not available")
+	}
+
+	return s.store, nil
+}
+
+// Add a ban, assigning it an ID and creation time if it does not have them
+func (s *BanService) Add(ban Ban) (Ban, error) {
+	store, err := s.getStore()
+
+	if err != nil {
+		return ban, err
+	}
+
+	if !ban.Valid() {
+		return ban, fmt.Errorf("a ban needs a valid account ID, identifier or network")
+	}
+
+	if ban.Id == "" {
+		ban.Id = NewId()
+	}
+
+	if ban.CreatedAt.IsZero() {
+		ban.CreatedAt = time.Now()
+	}
+
+	if err = store.Add(ban); err != nil {
+		return ban, err
+	}
+
+	expiry := "never"
+	if !ban.Permanent() {
+		expiry = ban.ExpiresAt.Format(time.RFC3339)
+	}
+
+	s.Infof("Added ban %s by %s (expires %s) - Reason: %s", ban.Id, ban.Issuer, expiry, ban.Reason)
+
+	return ban, nil
+}
+
+// TempBan bans an account for a while, such as after it is kicked.
+// Guest accounts have no account ID, so the address they connected from is banned instead.
+func (s *BanService) TempBan(account *types.Account, address netip.Addr, duration time.Duration, reason string, issuer string) error {
+	if duration <= 0 {
+		return nil
+	}
+
+	ban := Ban{
+		Reason:    reason,
+		Issuer:    issuer,
+		ExpiresAt: time.Now().Add(duration),
+	}
+
+	if account != nil {
+		ban.AccountId = account.Id
+		ban.Name = account.Name
+	}
+
+	if ban.AccountId == "" && address.IsValid() {
+		ban.Network = address.Unmap().String()
+	}
+
+	_, err := s.Add(ban)
+
+	return err
+}
+
+// Remove a ban by its ID
+func (s *BanService) Remove(id string) error {
+	store, err := s.getStore()
+
+	if err != nil {
+		return err
+	}
+
+	if err = store.Remove(id); err != nil {
+		return err
+	}
+
+	s.Infof("Removed ban %s", id)
+
+	return nil
+}
+
+// List the bans which have not expired
+func (s *BanService) List() ([]Ban, error) {
+	store, err := s.getStore()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return store.List()
+}
+
+// Check returns the ban which applies to a player, if there is one
+func (s *BanService) Check(subject Subject) (*Ban, error) {
+	store, err := s.getStore()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Find(store, subject, time.Now())
+}
@@ -0,0 +1,41 @@
+package bans
+
+import (
+	"errors"
+	"time"
+)
+
+// Returned when removing a ban which does not exist
+var ErrBanNotFound = errors.New("ban not found")
+
+// A Store keeps bans, so that they survive restarts
+type Store interface {
+	// Add a ban, replacing any ban with the same ID
+	Add(ban Ban) error
+
+	// Remove the ban with the given ID
+	Remove(id string) error
+
+	// List the bans which have not expired
+	List() ([]Ban, error)
+
+	// Close the store
+	Close() error
+}
+
+// Find the first ban in a store which applies to a player
+func Find(store Store, subject Subject, now time.Time) (*Ban, error) {
+	bans, err := store.List()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range bans {
+		if !bans[i].Expired(now) && bans[i].Matches(subject) {
+			return &bans[i], nil
+		}
+	}
+
+	return nil, nil
+}
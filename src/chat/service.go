@@ -0,0 +1,170 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/mods"
+	"github.com/altriusrs/netbeams/src/player_manager"
+	"github.com/altriusrs/netbeams/src/protocol"
+	"github.com/altriusrs/netbeams/src/tcp"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// The name that server originated messages are attributed to
+const ServerName = "Server"
+
+// A Chat service instance, which relays chat messages between players
+type ChatService struct {
+	types.Service
+	server *tcp.Server // The TCP server which chat is relayed through
+}
+
+// Create a new Chat service instance
+func Service() *ChatService {
+	service := &ChatService{
+		Service: types.SpinUp("Chat"),
+	}
+
+	service.RegisterServiceHooks(service.Start, service.Stop, nil)
+
+	return service
+}
+
+func (s *ChatService) Start() (types.Status, error) {
+	server, ok := types.App.GetService("TCP Server").(*tcp.Server)
+
+	if !ok {
+		return types.StatusErrored, fmt.Errorf("chat requires the TCP server")
+	}
+
+	s.server = server
+	s.server.Dispatcher.Register('C', s.HandleChat)
+
+	// Let players who are already connected know when the server content changes
+	if mm, ok := types.App.GetService("Mod Manager").(*mods.ModManager); ok {
+		mm.OnChange(s.AnnounceModChange)
+	}
+
+	// Let players know when they are running out of online time
+	if pm, ok := types.App.GetService("Player Manager").(*player_manager.PlayerManager); ok {
+		pm.OnQuota(s.AnnounceQuota)
+	}
+
+	return types.StatusHealthy, nil
+}
+
+func (s *ChatService) Stop() (types.Status, error) {
+	if s.server != nil {
+		s.server.Dispatcher.Unregister('C')
+	}
+
+	return types.StatusShutdown, nil
+}
+
+// HandleChat relays a chat message from a player to everyone on the server
+func (s *ChatService) HandleChat(c *tcp.TCPConnection, packet types.TcpPacket) {
+	var message protocol.Chat
+
+	if err := protocol.Decode(packet, &message); err != nil {
+		c.Warn(err.Error())
+		return
+	}
+
+	if strings.TrimSpace(message.Message) == "" {
+		return
+	}
+
+	// Attribute the message to the player, rather than trusting the name sent by the client
+	message.Name = c.GetPlayer().DisplayName
+
+	s.log(message)
+	s.server.Connections.Broadcast(protocol.Packet(&message), nil)
+}
+
+// AnnounceModChange tells every player that the mods on the server have changed
+func (s *ChatService) AnnounceModChange(added []mods.Mod, removed []mods.Mod) {
+	if len(added) > 0 {
+		s.SendServerMessage(fmt.Sprintf("New content is available (%s) - Rejoin the server to download it", modNames(added)))
+	} else if len(removed) > 0 {
+		s.SendServerMessage(fmt.Sprintf("Content has been removed from the server (%s)", modNames(removed)))
+	}
+}
+
+// AnnounceQuota warns a player that they are running out of online time
+func (s *ChatService) AnnounceQuota(id int, remaining time.Duration) {
+	if remaining <= 0 {
+		return
+	}
+
+	text := fmt.Sprintf("You have %s of online time remaining", formatRemaining(remaining))
+
+	if err := s.SendDirectMessage(id, text); err != nil {
+		s.Debugf("Unable to warn player %d about their online time - %s", id, err.Error())
+	}
+}
+
+// SendServerMessage sends a message to every player, attributed to the server
+func (s *ChatService) SendServerMessage(text string) {
+	message := protocol.Chat{Name: ServerName, Message: text}
+
+	s.log(message)
+	s.server.Connections.Broadcast(protocol.Packet(&message), nil)
+}
+
+// SendDirectMessage sends a message, attributed to the server, to a single player
+func (s *ChatService) SendDirectMessage(playerId int, text string) error {
+	c := s.server.Connections.GetByPlayerId(playerId)
+
+	if c == nil {
+		return fmt.Errorf("player %d is not connected", playerId)
+	}
+
+	message := protocol.Chat{Name: ServerName, Message: text}
+
+	if config.Configuration.General.LogChat {
+		s.Infof("[%s -> %s] %s", ServerName, c.GetPlayer().DisplayName, text)
+	}
+
+	c.Write(protocol.Packet(&message))
+
+	return nil
+}
+
+// Write a chat message to the log, if chat logging is enabled
+func (s *ChatService) log(message protocol.Chat) {
+	if config.Configuration.General.LogChat {
+		s.Infof("[%s] %s", message.Name, message.Message)
+	}
+}
+
+// List the names of mods for a chat message
+func modNames(list []mods.Mod) string {
+	names := make([]string, 0, len(list))
+
+	for _, mod := range list {
+		names = append(names, strings.TrimPrefix(mod.Name, "/"))
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+// Format a remaining time in whole minutes, or in seconds when less than a minute remains
+func formatRemaining(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%d seconds", int(d.Round(time.Second).Seconds()))
+	}
+
+	minutes := int(d.Round(time.Minute).Minutes())
+
+	if minutes == 1 {
+		return "1 minute"
+	}
+
+	return fmt.Sprintf("%d minutes", minutes)
+}
@@ -0,0 +1,53 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/altriusrs/netbeams/src/tcp/tcptest"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// Create a chat service with a TCP server which is not listening
+func newTestChat(t *testing.T) *ChatService {
+	t.Helper()
+
+	s := Service()
+	s.server = tcptest.NewServer(t)
+
+	return s
+}
+
+func TestHandleChatRelaysToEveryone(t *testing.T) {
+	s := newTestChat(t)
+	alice := tcptest.NewPlayer(t, s.server, 0, "Alice")
+	bob := tcptest.NewPlayer(t, s.server, 1, "Bob")
+
+	// The name sent by the client is replaced with the name of the player
+	s.HandleChat(alice.Connection, types.NewTcpPacket("C:Bob: hello"))
+
+	alice.Expect(t, "C:Alice: hello")
+	bob.Expect(t, "C:Alice: hello")
+
+	// Blank messages are dropped
+	s.HandleChat(alice.Connection, types.NewTcpPacket("C:Alice:    "))
+
+	alice.ExpectNothing(t)
+	bob.ExpectNothing(t)
+}
+
+func TestSendDirectMessageIsPrivate(t *testing.T) {
+	s := newTestChat(t)
+	alice := tcptest.NewPlayer(t, s.server, 0, "Alice")
+	bob := tcptest.NewPlayer(t, s.server, 1, "Bob")
+
+	if err := s.SendDirectMessage(1, "just for you"); err != nil {
+		t.Fatal(err)
+	}
+
+	bob.Expect(t, "C:Server: just for you")
+	alice.ExpectNothing(t)
+
+	if err := s.SendDirectMessage(7, "nobody"); err == nil {
+		t.Error("Expected sending to a missing player to fail")
+	}
+}
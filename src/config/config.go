@@ -91,11 +91,16 @@ func Load() {
 
 	}
 
-	config.Auth.Idle.MaxTimeTime, _ = time.ParseDuration(config.Auth.Idle.MaxTime)
-	config.Auth.Kick.IdleDurationTime, _ = time.ParseDuration(config.Auth.Kick.IdleDuration)
-	config.Auth.Kick.OnlineDurationTime, _ = time.ParseDuration(config.Auth.Kick.OnlineDuration)
-	config.Auth.Kick.AdminDurationTime, _ = time.ParseDuration(config.Auth.Kick.AdminDuration)
-	config.Auth.Online.QuotaTime, _ = time.ParseDuration(config.Auth.Online.Quota)
+	config.Auth.Idle.MaxTimeTime, _ = ParseDuration(config.Auth.Idle.MaxTime, time.Minute)
+	config.Auth.Kick.IdleDurationTime, _ = ParseDuration(config.Auth.Kick.IdleDuration, time.Second)
+	config.Auth.Kick.OnlineDurationTime, _ = ParseDuration(config.Auth.Kick.OnlineDuration, time.Second)
+	config.Auth.Kick.AdminDurationTime, _ = ParseDuration(config.Auth.Kick.AdminDuration, time.Second)
+	config.Auth.Online.QuotaTime, _ = ParseDuration(config.Auth.Online.Quota, time.Minute)
+	config.Auth.Ping.DurationTime, _ = time.ParseDuration(config.Auth.Ping.Duration)
+	config.NetBeams.WriteTimeoutTime, _ = time.ParseDuration(config.NetBeams.WriteTimeout)
+	config.NetBeams.PasswordLockoutTime, _ = time.ParseDuration(config.NetBeams.PasswordLockout)
+	config.NetBeams.RelayMidIntervalTime, _ = time.ParseDuration(config.NetBeams.RelayMidInterval)
+	config.NetBeams.RelayFarIntervalTime, _ = time.ParseDuration(config.NetBeams.RelayFarInterval)
 
 	Configuration = config
 
@@ -28,6 +28,28 @@ func LoadDefault() BaseConfig {
 			LogFile:    "/logs/netbeams.log",
 			ModServer:  "",
 			UseUPnP:    true,
+
+			WriteTimeout:  "10s",
+			SendQueueSize: 256,
+
+			PasswordAttempts: 5,
+			PasswordLockout:  "5m",
+
+			DownloadLimit:          0,
+			DownloadLimitPerClient: 0,
+
+			RelayNearDistance: 300,
+			RelayFarDistance:  1000,
+			RelayMidInterval:  "100ms",
+			RelayFarInterval:  "1s",
+
+			ShowPing: true,
+
+			BanStore: "file",
+			BanFile:  "Bans.json",
+
+			AdminAddress: "127.0.0.1:30816",
+			AdminToken:   "",
 		},
 		Auth: AuthenticationConfig{
 			AllowGuests:          true,
@@ -41,8 +63,9 @@ func LoadDefault() BaseConfig {
 			},
 
 			Online: AuthOnlineConfig{
-				Enable: false,
-				Quota:  "2 hours",
+				Enable:    false,
+				Quota:     "2 hours",
+				UsageFile: "OnlineUsage.json",
 			},
 
 			VPN: AuthVPNConfig{
@@ -61,6 +84,33 @@ func LoadDefault() BaseConfig {
 				},
 			},
 
+			Ping: AuthPingConfig{
+				Enable:   false,
+				MaxPing:  300,
+				Duration: "30s",
+			},
+
+			Messages: AuthMessagesConfig{
+				Guests:          "Guest accounts are not allowed on this server - Please log in to BeamMP to join",
+				ContentCreators: "Content creators are not allowed on this server",
+				Staff:           "BeamMP staff are not allowed on this server",
+				NotAllowed:      "You are not on the allow list for this server",
+				Blocked:         "You are blocked from this server",
+			},
+
+			Roles: map[string]AuthRoleConfig{},
+
+			AllowList: AllowList{
+				Enable:  false,
+				File:    "AllowList.txt",
+				Players: []string{},
+			},
+
+			BlockList: BlockList{
+				File:    "BlockList.txt",
+				Players: []string{},
+			},
+
 			Kick: AuthKickConfig{
 				AdminDuration:  "1 hour",
 				IdleDuration:   "5 minutes",
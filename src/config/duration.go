@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Units which may be written out in full in the config file
+var durationUnits = map[string]time.Duration{
+	"second":  time.Second,
+	"seconds": time.Second,
+	"minute":  time.Minute,
+	"minutes": time.Minute,
+	"hour":    time.Hour,
+	"hours":   time.Hour,
+	"day":     24 * time.Hour,
+	"days":    24 * time.Hour,
+}
+
+// ParseDuration reads a duration from the config file.
+// Go durations (eg. 10m), written out durations (eg. 10 minutes), and bare numbers in the given unit are accepted.
+// A negative value disables the setting it belongs to, and is returned as -1.
+func ParseDuration(value string, unit time.Duration) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+
+	if d, err := time.ParseDuration(value); err == nil {
+		return disabledIfNegative(d), nil
+	}
+
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return disabledIfNegative(time.Duration(n * float64(unit))), nil
+	}
+
+	if fields := strings.Fields(value); len(fields) == 2 {
+		n, err := strconv.ParseFloat(fields[0], 64)
+		u, ok := durationUnits[strings.ToLower(fields[1])]
+
+		if err == nil && ok {
+			return disabledIfNegative(time.Duration(n * float64(u))), nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid duration %q", value)
+}
+
+func disabledIfNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return -1
+	}
+
+	return d
+}
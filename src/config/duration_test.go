@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		unit  time.Duration
+		want  time.Duration
+	}{
+		{"10m", time.Second, 10 * time.Minute},
+		{"10 minutes", time.Second, 10 * time.Minute},
+		{"1 Hour", time.Second, time.Hour},
+		{"30", time.Second, 30 * time.Second},
+		{"30", time.Minute, 30 * time.Minute},
+		{"-1", time.Minute, -1},
+		{"0", time.Minute, 0},
+	}
+
+	for _, test := range tests {
+		got, err := ParseDuration(test.value, test.unit)
+
+		if err != nil {
+			t.Errorf("ParseDuration(%q) returned an error: %s", test.value, err.Error())
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("ParseDuration(%q) = %s, expected %s", test.value, got, test.want)
+		}
+	}
+
+	for _, value := range []string{"", "soon", "10 fortnights"} {
+		if _, err := ParseDuration(value, time.Second); err == nil {
+			t.Errorf("Expected ParseDuration(%q) to fail", value)
+		}
+	}
+}
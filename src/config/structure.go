@@ -1,6 +1,10 @@
 package config
 
-import "time"
+import (
+	"time"
+
+	"github.com/altriusrs/netbeams/src/types"
+)
 
 // BaseConfig is the main config struct for the server
 type BaseConfig struct {
@@ -55,8 +59,8 @@ type GeneralConfig struct {
 	// Folder to load resources from
 	ResourceFolder string `toml:"ResourceFolder" comment:""`
 
-	// Password to use for the server
-	Password string `toml:"Password" comment:""`
+	// Password to use for the server, either in plain text or as a bcrypt hash
+	Password string `toml:"Password" comment:"Password to use for the server, either in plain text or as a bcrypt hash created with 'netbeams hash-password <password>'\n Leave empty to disable"`
 }
 
 // MiscConfig is the miscellaneous server settings
@@ -92,19 +96,76 @@ type NetBeamsConfig struct {
 
 	// Whether to use UPnP to automatically map the server to a port on the router
 	UseUPnP bool `toml:"UseUPnP" comment:"Whether to use UPnP to automatically map the server to a port on the router"`
+
+	// The maximum amount of time a single write to a client may take before the client is disconnected
+	WriteTimeout string `toml:"WriteTimeout" comment:"The maximum amount of time a single write to a client may take before the client is disconnected (eg. 10s)"`
+
+	// The write timeout in Go Time format
+	WriteTimeoutTime time.Duration
+
+	// The number of packets which may be waiting to be sent to a client before it is disconnected
+	SendQueueSize int `toml:"SendQueueSize" comment:"The number of packets which may be waiting to be sent to a client before it is disconnected as a slow client"`
+
+	// The number of wrong passwords an IP address may send before it is locked out
+	PasswordAttempts int `toml:"PasswordAttempts" comment:"The number of wrong passwords an IP address may send before it is locked out"`
+
+	// How long an IP address is locked out for after sending too many wrong passwords
+	PasswordLockout string `toml:"PasswordLockout" comment:"How long an IP address is locked out for after sending too many wrong passwords (eg. 5m)"`
+
+	// The password lockout in Go Time format
+	PasswordLockoutTime time.Duration
+
+	// The maximum rate mod downloads may use across all clients, in kilobytes per second
+	DownloadLimit int `toml:"DownloadLimit" comment:"The maximum rate mod downloads may use across all clients, in kilobytes per second\n Set to 0 to disable"`
+
+	// The maximum rate mod downloads may use for a single client, in kilobytes per second
+	DownloadLimitPerClient int `toml:"DownloadLimitPerClient" comment:"The maximum rate mod downloads may use for a single client, in kilobytes per second\n Set to 0 to disable"`
+
+	// Vehicles closer than this distance (in meters) to a player have every position update relayed to them
+	RelayNearDistance int `toml:"RelayNearDistance" comment:"Vehicles closer than this distance (in meters) to a player have every position update relayed to them"`
+
+	// Vehicles further than this distance (in meters) from a player have position updates relayed at the far interval
+	RelayFarDistance int `toml:"RelayFarDistance" comment:"Vehicles further than this distance (in meters) from a player have position updates relayed at the far interval"`
+
+	// The minimum time between position updates for vehicles between the near and far distances
+	RelayMidInterval string `toml:"RelayMidInterval" comment:"The minimum time between position updates for vehicles between the near and far distances (eg. 100ms)"`
+
+	// The relay mid interval in Go Time format
+	RelayMidIntervalTime time.Duration
+
+	// The minimum time between position updates for vehicles beyond the far distance
+	RelayFarInterval string `toml:"RelayFarInterval" comment:"The minimum time between position updates for vehicles beyond the far distance (eg. 1s)"`
+
+	// The relay far interval in Go Time format
+	RelayFarIntervalTime time.Duration
+
+	// Whether the ping of each player is shown next to their name in the in-game player list
+	ShowPing bool `toml:"ShowPing" comment:"Whether the ping of each player is shown next to their name in the in-game player list"`
+
+	// Where bans are stored
+	BanStore string `toml:"BanStore" comment:"Where bans are stored, either 'file' or 'keyval' (valkey, configured with the VALKEY_URI and VALKEY_PASSWORD environment variables)"`
+
+	// The file bans are stored in
+	BanFile string `toml:"BanFile" comment:"The file bans are stored in, when the ban store is 'file' or valkey is unavailable\n The server will not start if this file cannot be read, so that players are never let in without their bans being checked"`
+
+	// The address the admin API listens on
+	AdminAddress string `toml:"AdminAddress" comment:"The address the admin API (player list, metrics, bans and kicks) listens on (eg. 127.0.0.1:30816)\n Metrics are served without the admin token, and include the name and ping of each player\n Leave empty to disable"`
+
+	// The bearer token required to list players, manage bans and kick players through the admin API
+	AdminToken string `toml:"AdminToken" comment:"The bearer token required to list players, manage bans and kick players through the admin API, either in plain text or as a bcrypt hash created with 'netbeams hash-password <token>'\n Leave empty to disable the players, bans and kick endpoints"`
 }
 
 // AuthenticationConfig is the authentication settings specific to NetBeams
 type AuthenticationConfig struct {
 
 	// Whether guests are allowed to join the server
-	AllowGuests bool `toml:"AllowGuests" comment:"Whether guest accounts are allowed to join the server\nThis will automatically prevent guest accounts from joining the server in the authentication step."`
+	AllowGuests bool `toml:"AllowGuests" comment:"Whether guest accounts are allowed to join the server\nThis will automatically prevent guest accounts from joining the server in the authentication step, even if they are on the allow list."`
 
 	// Whether content creators are allowed to join the server
-	AllowContentCreators bool `toml:"AllowContentCreators" comment:"Whether content creators are allowed to join the server\nThis will automatically prevent content creators from joining the server in the authentication step."`
+	AllowContentCreators bool `toml:"AllowContentCreators" comment:"Whether content creators are allowed to join the server\nThis will automatically prevent content creators from joining the server in the authentication step, even if they are on the allow list."`
 
 	// Whether BeamMP staff are allowed to join the server
-	AllowStaff bool `toml:"AllowStaff" comment:"Whether BeamMP staff are allowed to join the server\nThis will automatically prevent BeamMP staff from joining the server in the authentication step."`
+	AllowStaff bool `toml:"AllowStaff" comment:"Whether BeamMP staff are allowed to join the server\nThis will automatically prevent BeamMP staff from joining the server in the authentication step, even if they are on the allow list."`
 
 	// The minimum age of an account to be able to join the server
 	// MinimumAccountAge string `toml:"MinimumAccountAge" comment:"The minimum age of an account to be able to join the server\n Leave empty to disable\n Currently does not work, as the server does not know the age of the account"`
@@ -121,13 +182,52 @@ type AuthenticationConfig struct {
 	// Proxy detection settings
 	Proxy AuthProxyConfig `toml:"Proxy" comment:"Proxy detection settings"`
 
+	// High ping detection settings
+	Ping AuthPingConfig `toml:"Ping" comment:"High ping detection settings"`
+
 	// Kick player detection settings
 	Kick AuthKickConfig `toml:"Kick" comment:"Kick player detection settings"`
 
+	// The messages shown to players who are not allowed to join
+	Messages AuthMessagesConfig `toml:"Messages" comment:"The messages shown to players who are not allowed to join the server"`
+
+	// Rules for players with particular BeamMP roles
+	Roles map[string]AuthRoleConfig `toml:"Roles" comment:"Rules for players with particular BeamMP roles, keyed by role (eg. USER, EA, YT, STAFF)"`
+
+	// Players who may join the server
+	AllowList AllowList `toml:"AllowList" comment:"Players who may join the server"`
+
+	// Players who are blocked from joining the server
+	BlockList BlockList `toml:"BlockList" comment:"Players who are blocked from joining the server"`
+
 	// Admin player detection settings
 	Admin AuthAdminConfig `toml:"Admin" comment:"Admin player detection settings"`
 }
 
+type AuthMessagesConfig struct {
+
+	// The message shown to guests when guests are not allowed
+	Guests string `toml:"Guests" comment:"The message shown to guests when guests are not allowed"`
+
+	// The message shown to content creators when content creators are not allowed
+	ContentCreators string `toml:"ContentCreators" comment:"The message shown to content creators when content creators are not allowed"`
+
+	// The message shown to BeamMP staff when staff are not allowed
+	Staff string `toml:"Staff" comment:"The message shown to BeamMP staff when staff are not allowed"`
+
+	// The message shown to players who are not on the allow list
+	NotAllowed string `toml:"NotAllowed" comment:"The message shown to players who are not on the allow list, when it is enabled"`
+
+	// The message shown to players who are on the block list
+	Blocked string `toml:"Blocked" comment:"The message shown to players who are on the block list"`
+}
+
+type AuthRoleConfig struct {
+
+	// The maximum number of vehicles a player with the role may spawn
+	MaxCars int `toml:"MaxCars" comment:"The maximum number of vehicles a player with the role may spawn\n Set to 0 to use the server limit, or -1 for no limit"`
+}
+
 type AuthIdleConfig struct {
 
 	// Whether idle player detection is enabled
@@ -139,8 +239,8 @@ type AuthIdleConfig struct {
 	// The max time in Go Time format
 	MaxTimeTime time.Duration
 
-	// The minimum distance a player must have moved to not be considered idle
-	MinDistance int `toml:"MinDistance" comment:"The minimum distance a player must have moved to not be considered idle"`
+	// The minimum distance (in meters) a player must have moved within the max time to not be considered idle
+	MinDistance int `toml:"MinDistance" comment:"The minimum distance (in meters) a player must have moved within the max time to not be considered idle"`
 }
 
 type AuthKickConfig struct {
@@ -174,6 +274,24 @@ type AuthOnlineConfig struct {
 
 	// The quota time in Go Time format
 	QuotaTime time.Duration
+
+	// The file online time is saved to, so that it survives restarts
+	UsageFile string `toml:"UsageFile" comment:"The file online time is saved to, so that it survives restarts"`
+}
+
+type AuthPingConfig struct {
+
+	// Whether players should be kicked for a sustained high ping
+	Enable bool `toml:"Enable" comment:"Whether players should be kicked for a sustained high ping"`
+
+	// The highest ping a player may have, in milliseconds
+	MaxPing int `toml:"MaxPing" comment:"The highest ping a player may have, in milliseconds"`
+
+	// How long a player's ping may stay above the limit before they are kicked
+	Duration string `toml:"Duration" comment:"How long a player's ping may stay above the limit before they are kicked (eg. 30s)"`
+
+	// The duration in Go Time format
+	DurationTime time.Duration
 }
 
 type AuthVPNConfig struct {
@@ -234,14 +352,101 @@ type PlayerPermissionsConfig struct {
 	MutePlayers bool `toml:"MutePlayers" comment:"Whether the user can mute other players"`
 }
 
+// Convert the permissions into the form stored on a player
+func (p PlayerPermissionsConfig) IntoPlayerPermissions() types.PlayerPermissionsConfig {
+	return types.PlayerPermissionsConfig{
+		BypassIdle:     p.BypassIdle,
+		BypassOnline:   p.BypassOnline,
+		BypassVehicles: p.BypassVehicles,
+		HideName:       p.HideName,
+		KickPlayers:    p.KickPlayers,
+		BanPlayers:     p.BanPlayers,
+		MutePlayers:    p.MutePlayers,
+	}
+}
+
+// Get the permissions configured for an account, checking by name and then by ID
+func PermissionsFor(account *types.Account) types.PlayerPermissionsConfig {
+	if account == nil {
+		return types.PlayerPermissionsConfig{}
+	}
+
+	if permissions, ok := Configuration.Auth.Admin[account.Name]; ok {
+		return permissions.IntoPlayerPermissions()
+	}
+
+	if permissions, ok := Configuration.Auth.Admin[account.Id]; ok {
+		return permissions.IntoPlayerPermissions()
+	}
+
+	return types.PlayerPermissionsConfig{}
+}
+
+// Admit decides whether an account may join the server based on its roles, returning the message to kick it with if not
+func Admit(account *types.Account) (bool, string) {
+	auth := Configuration.Auth
+
+	switch {
+	case account == nil:
+		return true, ""
+	case account.Guest && !auth.AllowGuests:
+		return false, auth.Messages.Guests
+	case account.IsContentCreator() && !auth.AllowContentCreators:
+		return false, auth.Messages.ContentCreators
+	case account.IsStaff() && !auth.AllowStaff:
+		return false, auth.Messages.Staff
+	}
+
+	return true, ""
+}
+
+// MaxCarsFor returns the number of vehicles an account may spawn, or -1 if there is no limit.
+// The most generous rule for the roles of the account applies, falling back to the server limit.
+func MaxCarsFor(account *types.Account) int {
+	limit := 0
+
+	if account != nil {
+		for _, role := range account.Roles {
+			rule, ok := Configuration.Auth.Roles[string(role)]
+
+			if !ok || rule.MaxCars == 0 {
+				continue
+			}
+
+			if rule.MaxCars < 0 {
+				return -1
+			}
+
+			if rule.MaxCars > limit {
+				limit = rule.MaxCars
+			}
+		}
+	}
+
+	if limit == 0 {
+		return Configuration.General.MaxCars
+	}
+
+	return limit
+}
+
 type AllowList struct {
 
+	// Whether only players on the allow list may join the server
+	Enable bool `toml:"Enable" comment:"Whether only players on the allow list may join the server\n Players are checked against the block list, then the role rules (AllowGuests, AllowContentCreators and AllowStaff), then the allow list, then bans\n Being on the allow list does not let a player past the block list, role rules or bans"`
+
+	// A file containing more players, one per line, which is reloaded when it changes
+	File string `toml:"File" comment:"A file containing more players, one name, account ID or identifier per line (lines starting with # are ignored)\n It is reloaded whenever it changes - Leave empty to disable"`
+
 	// A list of players that are allowed to join the server - These players will be able to join the server only if they pass all other authentication checks
-	Players []string `toml:"Players" comment:"A list of players that are allowed to join the server - These players will be able to join the server only if they pass all other authentication checks"`
+	Players []string `toml:"Players" comment:"A list of players (names, account IDs or identifiers) that are allowed to join the server - These players will be able to join the server only if they pass all other authentication checks"`
 }
 
 type BlockList struct {
 
+	// A file containing more players, one per line, which is reloaded when it changes
+	File string `toml:"File" comment:"A file containing more players, one name, account ID or identifier per line (lines starting with # are ignored)\n It is reloaded whenever it changes - Leave empty to disable"`
+
 	// A list of players that are blocked from joining the server - This is effectively a perma-ban
-	Players []string `toml:"Players" comment:"A list of players that are blocked from joining the server - This is effectively a perma-ban"`
+	Players []string `toml:"Players" comment:"A list of players (names, account IDs or identifiers) that are blocked from joining the server - This is effectively a perma-ban"`
 }
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/altriusrs/netbeams/src/types"
+	"github.com/pelletier/go-toml"
+)
+
+func TestRoleRules(t *testing.T) {
+	content := `
+[General]
+MaxCars = 2
+
+[Auth]
+AllowGuests = false
+AllowContentCreators = true
+AllowStaff = false
+
+[Auth.Roles.ea]
+MaxCars = 4
+
+[Auth.Roles.STAFF]
+MaxCars = -1
+`
+
+	var config BaseConfig
+	if err := toml.Unmarshal([]byte(content), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	config.Validate()
+	Configuration = config
+
+	if config.Auth.Messages.Guests == "" {
+		t.Error("Expected missing messages to use their default")
+	}
+
+	cases := []struct {
+		name     string
+		account  *types.Account
+		admitted bool
+		maxCars  int
+	}{
+		{"user", &types.Account{Roles: types.ParseRoles("USER")}, true, 2},
+		{"early access", &types.Account{Roles: types.ParseRoles("EA")}, true, 4},
+		{"content creator", &types.Account{Roles: types.ParseRoles("YT")}, true, 2},
+		{"guest", &types.Account{Guest: true}, false, 2},
+		{"staff", &types.Account{Roles: types.ParseRoles("STAFF")}, false, -1},
+	}
+
+	for _, c := range cases {
+		admitted, message := Admit(c.account)
+
+		if admitted != c.admitted {
+			t.Errorf("%s: expected admitted to be %t, got %t", c.name, c.admitted, admitted)
+		}
+
+		if !admitted && message == "" {
+			t.Errorf("%s: expected a kick message", c.name)
+		}
+
+		if maxCars := MaxCarsFor(c.account); maxCars != c.maxCars {
+			t.Errorf("%s: expected a limit of %d vehicles, got %d", c.name, c.maxCars, maxCars)
+		}
+	}
+}
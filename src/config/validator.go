@@ -2,6 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/crypto"
 )
 
 // A ConfigError represents a single error in a config file
@@ -44,9 +49,12 @@ func (c *BaseConfig) Validate() []ConfigError {
 
 	netBeamsErrors := c.NetBeams.Validate()
 
+	authErrors := c.Auth.Validate()
+
 	errors = append(errors, baseErrors...)
 	errors = append(errors, miscErrors...)
 	errors = append(errors, netBeamsErrors...)
+	errors = append(errors, authErrors...)
 
 	if c.General.Port == c.NetBeams.MasterPort {
 		errors = append(errors, ConfigError{
@@ -127,13 +135,23 @@ func (c *GeneralConfig) Validate() []ConfigError {
 		})
 	}
 
-	if len(c.Password) > 0 {
-		c.Password = ""
+	if crypto.IsPasswordHash(c.Password) {
+		if err := crypto.ValidatePasswordHash(c.Password); err != nil {
+			errors = append(errors, ConfigError{
+				code:        0x0007,
+				message:     "Invalid password hash",
+				details:     "Password should be created with `netbeams hash-password <password>`",
+				usesDefault: false,
+				fatal:       true,
+				warning:     false,
+			})
+		}
+	} else if len(c.Password) > 0 {
 		errors = append(errors, ConfigError{
-			code:        0x0007,
-			message:     "Unsupported Parameter",
-			details:     "passwords are not supported by NetBeams",
-			usesDefault: true,
+			code:        0x0008,
+			message:     "Plain text password",
+			details:     "It is recommended to store a hash of the password, created with `netbeams hash-password <password>`",
+			usesDefault: false,
 			fatal:       false,
 			warning:     true,
 		})
@@ -192,5 +210,359 @@ func (c *NetBeamsConfig) Validate() []ConfigError {
 		})
 	}
 
+	if _, err := time.ParseDuration(c.WriteTimeout); err != nil {
+		c.WriteTimeout = "10s" // default
+		errors = append(errors, ConfigError{
+			code:        0x0400,
+			message:     "Invalid write timeout",
+			details:     "Write timeout must be a duration such as 10s - Will use default value (10s)",
+			usesDefault: true,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
+	if c.SendQueueSize < 1 {
+		c.SendQueueSize = 256 // default
+		errors = append(errors, ConfigError{
+			code:        0x0500,
+			message:     "Invalid send queue size",
+			details:     "Send queue size must be at least 1 - Will use default value (256)",
+			usesDefault: true,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
+	if c.PasswordAttempts < 1 {
+		c.PasswordAttempts = 5 // default
+		errors = append(errors, ConfigError{
+			code:        0x0600,
+			message:     "Invalid password attempts",
+			details:     "Password attempts must be at least 1 - Will use default value (5)",
+			usesDefault: true,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
+	if _, err := time.ParseDuration(c.PasswordLockout); err != nil {
+		c.PasswordLockout = "5m" // default
+		errors = append(errors, ConfigError{
+			code:        0x0700,
+			message:     "Invalid password lockout",
+			details:     "Password lockout must be a duration such as 5m - Will use default value (5m)",
+			usesDefault: true,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
+	if c.DownloadLimit < 0 {
+		c.DownloadLimit = 0 // default
+		errors = append(errors, ConfigError{
+			code:        0x0800,
+			message:     "Invalid download limit",
+			details:     "Download limit must be at least 0 - Will use default value (0, unlimited)",
+			usesDefault: true,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
+	if c.DownloadLimitPerClient < 0 {
+		c.DownloadLimitPerClient = 0 // default
+		errors = append(errors, ConfigError{
+			code:        0x0900,
+			message:     "Invalid per client download limit",
+			details:     "Per client download limit must be at least 0 - Will use default value (0, unlimited)",
+			usesDefault: true,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
+	if c.RelayNearDistance < 0 {
+		c.RelayNearDistance = 300 // default
+		errors = append(errors, ConfigError{
+			code:        0x0A00,
+			message:     "Invalid relay near distance",
+			details:     "Relay near distance must be at least 0 - Will use default value (300)",
+			usesDefault: true,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
+	if c.RelayFarDistance < c.RelayNearDistance {
+		c.RelayFarDistance = c.RelayNearDistance
+		errors = append(errors, ConfigError{
+			code:        0x0B00,
+			message:     "Invalid relay far distance",
+			details:     "Relay far distance must not be less than the near distance - Will use the near distance",
+			usesDefault: false,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
+	if _, err := time.ParseDuration(c.RelayMidInterval); err != nil {
+		c.RelayMidInterval = "100ms" // default
+		errors = append(errors, ConfigError{
+			code:        0x0C00,
+			message:     "Invalid relay mid interval",
+			details:     "Relay mid interval must be a duration such as 100ms - Will use default value (100ms)",
+			usesDefault: true,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
+	if _, err := time.ParseDuration(c.RelayFarInterval); err != nil {
+		c.RelayFarInterval = "1s" // default
+		errors = append(errors, ConfigError{
+			code:        0x0D00,
+			message:     "Invalid relay far interval",
+			details:     "Relay far interval must be a duration such as 1s - Will use default value (1s)",
+			usesDefault: true,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
+	switch c.BanStore {
+	case "file", "keyval":
+		// Nothing to validate here
+	default:
+		c.BanStore = "file" // default
+		errors = append(errors, ConfigError{
+			code:        0x0F00,
+			message:     "Invalid ban store",
+			details:     "Ban store must be one of file, keyval - Will use default value (file)",
+			usesDefault: true,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
+	if c.BanFile == "" {
+		c.BanFile = "Bans.json" // default
+		errors = append(errors, ConfigError{
+			code:        0x1100,
+			message:     "Invalid ban file",
+			details:     "Ban file should be set - Will use default value (Bans.json)",
+			usesDefault: true,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
+	if c.AdminAddress != "" {
+		if _, _, err := net.SplitHostPort(c.AdminAddress); err != nil {
+			c.AdminAddress = "" // disable
+			errors = append(errors, ConfigError{
+				code:        0x0E00,
+				message:     "Invalid admin address",
+				details:     "Admin address must be a host and port such as 127.0.0.1:30816 - The admin API will be disabled",
+				usesDefault: false,
+				fatal:       false,
+				warning:     true,
+			})
+		}
+	}
+
+	if c.AdminAddress != "" && c.AdminToken == "" {
+		errors = append(errors, ConfigError{
+			code:        0x1200,
+			message:     "Admin token not set",
+			details:     "The player list, bans and kicks cannot be reached through the admin API until an admin token is set",
+			usesDefault: false,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
+	return errors
+}
+
+func (c *AuthenticationConfig) Validate() []ConfigError {
+	errors := []ConfigError{}
+
+	if c.Idle.Enable {
+		if _, err := ParseDuration(c.Idle.MaxTime, time.Minute); err != nil {
+			c.Idle.MaxTime = "10 minutes" // default
+			errors = append(errors, ConfigError{
+				code:        0x0030,
+				message:     "Invalid idle max time",
+				details:     "Idle max time must be a duration such as 10 minutes - Will use default value (10 minutes)",
+				usesDefault: true,
+				fatal:       false,
+				warning:     true,
+			})
+		}
+
+		if c.Idle.MinDistance < 0 {
+			c.Idle.MinDistance = 100 // default
+			errors = append(errors, ConfigError{
+				code:        0x0040,
+				message:     "Invalid idle min distance",
+				details:     "Idle min distance must be at least 0 - Will use default value (100)",
+				usesDefault: true,
+				fatal:       false,
+				warning:     true,
+			})
+		}
+
+		if _, err := ParseDuration(c.Kick.IdleDuration, time.Second); err != nil {
+			c.Kick.IdleDuration = "5 minutes" // default
+			errors = append(errors, ConfigError{
+				code:        0x0050,
+				message:     "Invalid idle kick duration",
+				details:     "Idle kick duration must be a duration such as 5 minutes - Will use default value (5 minutes)",
+				usesDefault: true,
+				fatal:       false,
+				warning:     true,
+			})
+		}
+	}
+
+	if c.Online.Enable {
+		if quota, err := ParseDuration(c.Online.Quota, time.Minute); err != nil || quota == 0 {
+			c.Online.Quota = "2 hours" // default
+			errors = append(errors, ConfigError{
+				code:        0x0060,
+				message:     "Invalid online quota",
+				details:     "Online quota must be a duration such as 2 hours - Will use default value (2 hours)",
+				usesDefault: true,
+				fatal:       false,
+				warning:     true,
+			})
+		}
+
+		if _, err := ParseDuration(c.Kick.OnlineDuration, time.Second); err != nil {
+			c.Kick.OnlineDuration = "30 minutes" // default
+			errors = append(errors, ConfigError{
+				code:        0x0070,
+				message:     "Invalid online kick duration",
+				details:     "Online kick duration must be a duration such as 30 minutes - Will use default value (30 minutes)",
+				usesDefault: true,
+				fatal:       false,
+				warning:     true,
+			})
+		}
+
+		if c.Online.UsageFile == "" {
+			c.Online.UsageFile = "OnlineUsage.json" // default
+			errors = append(errors, ConfigError{
+				code:        0x0080,
+				message:     "Invalid online usage file",
+				details:     "Online usage file should be set - Will use default value (OnlineUsage.json)",
+				usesDefault: true,
+				fatal:       false,
+				warning:     true,
+			})
+		}
+	}
+
+	if c.AllowList.Enable && len(c.AllowList.Players) == 0 && c.AllowList.File == "" {
+		errors = append(errors, ConfigError{
+			code:        0x0090,
+			message:     "Empty allow list",
+			details:     "The allow list is enabled but has no players or file - Nobody will be able to join",
+			usesDefault: false,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
+	errors = append(errors, c.Messages.Validate()...)
+
+	for role, rule := range c.Roles {
+		if rule.MaxCars < -1 {
+			rule.MaxCars = 0 // default
+			c.Roles[role] = rule
+			errors = append(errors, ConfigError{
+				code:        0x00B0,
+				message:     "Invalid role max cars",
+				details:     fmt.Sprintf("Max cars for role %s must be at least -1 - Will use the server limit", role),
+				usesDefault: true,
+				fatal:       false,
+				warning:     true,
+			})
+		}
+
+		if upper := strings.ToUpper(role); upper != role {
+			delete(c.Roles, role)
+			c.Roles[upper] = rule
+		}
+	}
+
+	if c.Ping.Enable {
+		errors = append(errors, c.Ping.Validate()...)
+	}
+
+	return errors
+}
+
+func (c *AuthMessagesConfig) Validate() []ConfigError {
+	errors := []ConfigError{}
+	defaults := LoadDefault().Auth.Messages
+
+	messages := []struct {
+		name     string
+		value    *string
+		fallback string
+	}{
+		{"guests", &c.Guests, defaults.Guests},
+		{"content creators", &c.ContentCreators, defaults.ContentCreators},
+		{"staff", &c.Staff, defaults.Staff},
+		{"not allowed", &c.NotAllowed, defaults.NotAllowed},
+		{"blocked", &c.Blocked, defaults.Blocked},
+	}
+
+	for _, m := range messages {
+		if *m.value == "" {
+			*m.value = m.fallback // default
+			errors = append(errors, ConfigError{
+				code:        0x00A0,
+				message:     "Missing kick message",
+				details:     fmt.Sprintf("The %s message should be set - Will use default value (%s)", m.name, m.fallback),
+				usesDefault: true,
+				fatal:       false,
+				warning:     true,
+			})
+		}
+	}
+
+	return errors
+}
+
+func (c *AuthPingConfig) Validate() []ConfigError {
+	errors := []ConfigError{}
+
+	if c.MaxPing < 1 {
+		c.MaxPing = 300 // default
+		errors = append(errors, ConfigError{
+			code:        0x0010,
+			message:     "Invalid max ping",
+			details:     "Max ping must be at least 1 - Will use default value (300)",
+			usesDefault: true,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
+	if _, err := time.ParseDuration(c.Duration); err != nil {
+		c.Duration = "30s" // default
+		errors = append(errors, ConfigError{
+			code:        0x0020,
+			message:     "Invalid ping duration",
+			details:     "Ping duration must be a duration such as 30s - Will use default value (30s)",
+			usesDefault: true,
+			fatal:       false,
+			warning:     true,
+		})
+	}
+
 	return errors
 }
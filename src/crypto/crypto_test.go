@@ -3,6 +3,7 @@ package crypto
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -70,3 +71,66 @@ func TestCompareFileHashes(t *testing.T) {
 		t.Fatal("Hashes don't match")
 	}
 }
+
+func TestComparePassword(t *testing.T) {
+	hash, err := HashPassword("hunter2")
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !IsPasswordHash(hash) {
+		t.Fatalf("Expected %s to be a password hash", hash)
+	}
+
+	if err := ValidatePasswordHash(hash); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !ComparePassword("hunter2", hash) {
+		t.Error("Expected the password to match its hash")
+	}
+
+	if ComparePassword("hunter3", hash) {
+		t.Error("Expected a different password not to match the hash")
+	}
+
+	if !ComparePassword("hunter2", "hunter2") {
+		t.Error("Expected the password to match the plain text password")
+	}
+
+	if ComparePassword("hunter", "hunter2") {
+		t.Error("Expected a different password not to match the plain text password")
+	}
+}
+
+func TestValidatePasswordHash(t *testing.T) {
+	hash, err := HashPassword("hunter2")
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []string{
+		"$2a$",
+		"$2a$xx$" + hash[7:],
+		"$2a$99$" + hash[7:],
+		hash[:PasswordHashLength-1],
+	}
+
+	for _, hash := range tests {
+		if err := ValidatePasswordHash(hash); err == nil {
+			t.Errorf("Expected %s to be rejected", hash)
+		}
+
+		if ComparePassword("", hash) {
+			t.Errorf("Expected %s not to match any password", hash)
+		}
+	}
+}
+
+func TestHashPasswordTooLong(t *testing.T) {
+	if _, err := HashPassword(strings.Repeat("a", 73)); err == nil {
+		t.Error("Expected a password longer than bcrypt supports to be rejected")
+	}
+}
@@ -0,0 +1,76 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The prefixes which mark a password as a bcrypt hash rather than plain text
+//
+//	$2a$<cost>$<salt and hash>
+var PasswordHashPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+
+// The bcrypt cost used for new password hashes
+const PasswordCost = bcrypt.DefaultCost
+
+// The length of an encoded bcrypt hash
+const PasswordHashLength = 60
+
+var ErrMalformedPasswordHash = errors.New("malformed password hash")
+
+// HashPassword creates a bcrypt hash of a password, suitable for storing in the config file
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// IsPasswordHash reports whether a configured password is stored as a bcrypt hash
+func IsPasswordHash(s string) bool {
+	for _, prefix := range PasswordHashPrefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidatePasswordHash checks that a bcrypt hash can be used to verify passwords
+func ValidatePasswordHash(stored string) error {
+	if !IsPasswordHash(stored) || len(stored) != PasswordHashLength {
+		return ErrMalformedPasswordHash
+	}
+
+	if _, err := bcrypt.Cost([]byte(stored)); err != nil {
+		return ErrMalformedPasswordHash
+	}
+
+	return nil
+}
+
+// ComparePassword checks a password against the configured password, which may be plain text or a bcrypt hash.
+// The comparison takes constant time with respect to the contents of the password.
+func ComparePassword(password string, stored string) bool {
+	if !IsPasswordHash(stored) {
+		// Hashing both sides keeps the comparison from leaking the length of the password
+		expected := sha256.Sum256([]byte(stored))
+		given := sha256.Sum256([]byte(password))
+		return subtle.ConstantTimeCompare(expected[:], given[:]) == 1
+	}
+
+	if ValidatePasswordHash(stored) != nil {
+		return false
+	}
+
+	// bcrypt compares the derived hashes in constant time
+	return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil
+}
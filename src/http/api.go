@@ -14,13 +14,15 @@ import (
 // API is a wrapper around http.Client tailored for the BeamMP API
 type API struct {
 	types.Service
-	client *http.Client
+	BaseURL string // The address of the BeamMP authentication API
+	client  *http.Client
 }
 
 func Service() *API {
 
 	api := API{
 		Service: types.SpinUp("BeamMP API"),
+		BaseURL: types.BaseAuthAPIURL,
 		client: &http.Client{
 			Transport: &http.Transport{
 				MaxIdleConns:       5,
@@ -48,7 +50,7 @@ func (a *API) Stop() (types.Status, error) {
 // AuthenticatePlayer authenticates a player with the BeamMP API and returns a Player object
 // if successful, or nil and an error if not
 func (a *API) AuthenticatePlayer(key string) (*Player, error) {
-	url := fmt.Sprintf("%s/pkToUser", types.BaseAuthAPIURL)
+	url := fmt.Sprintf("%s/pkToUser", a.BaseURL)
 
 	body := map[string]string{
 		"key": key,
@@ -32,7 +32,7 @@ func (p *Player) IntoPlayerEntity() types.Player {
 			Id:          p.Id,
 			Guest:       p.Guest,
 			Identifiers: p.Identifiers,
-			Roles:       []string{p.Roles},
+			Roles:       types.ParseRoles(p.Roles),
 			UserId:      userId,
 		},
 		PublicKey: p.PublicKey,
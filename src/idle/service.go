@@ -0,0 +1,150 @@
+package idle
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/chat"
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/tcp"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// How often the vehicle positions of each player are sampled
+const SampleInterval = 5 * time.Second
+
+// How long before being kicked a player is warned (at most half of the idle time)
+const WarningLead = time.Minute
+
+// The message shown to players who are kicked for being idle
+const KickReason = "Kicked for being idle"
+
+// An Idle service instance, which kicks players whose vehicles have not moved far enough
+type IdleService struct {
+	types.Service
+	server   *tcp.Server                     // The TCP server which players are connected to
+	mutex    sync.Mutex                      // Guards the trackers
+	trackers map[*tcp.TCPConnection]*Tracker // The movement of each player
+}
+
+// Create a new Idle service instance
+func Service() *IdleService {
+	service := &IdleService{
+		Service:  types.SpinUp("Idle"),
+		trackers: make(map[*tcp.TCPConnection]*Tracker),
+	}
+
+	service.RegisterServiceHooks(service.Start, service.Stop, nil)
+
+	return service
+}
+
+func (s *IdleService) Start() (types.Status, error) {
+	settings := config.Configuration.Auth.Idle
+
+	if !settings.Enable || settings.MaxTimeTime <= 0 {
+		s.Info("Idle detection disabled")
+		return types.StatusHealthy, nil
+	}
+
+	server, ok := types.App.GetService("TCP Server").(*tcp.Server)
+
+	if !ok {
+		return types.StatusErrored, fmt.Errorf("idle detection requires the TCP server")
+	}
+
+	s.server = server
+	s.server.Connections.OnRemove(s.Forget)
+
+	go s.Loop()
+
+	return types.StatusHealthy, nil
+}
+
+func (s *IdleService) Stop() (types.Status, error) {
+	return types.StatusShutdown, nil
+}
+
+// Forget the movement of a player who has left the server
+func (s *IdleService) Forget(c *tcp.TCPConnection) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.trackers, c)
+}
+
+// Loop samples the vehicle positions of each player until the service stops
+func (s *IdleService) Loop() {
+	ticker := time.NewTicker(SampleInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if *s.Status != types.StatusHealthy {
+			return
+		}
+
+		for _, c := range s.server.Connections.Snapshot() {
+			s.check(c, now)
+		}
+	}
+}
+
+// Sample the vehicles of a player, and warn or kick them if they are idle
+func (s *IdleService) check(c *tcp.TCPConnection, now time.Time) {
+	if c.GetState() != types.StatePlaying {
+		return
+	}
+
+	settings := config.Configuration.Auth.Idle
+	lead := WarningLead
+
+	if lead > settings.MaxTimeTime/2 {
+		lead = settings.MaxTimeTime / 2
+	}
+
+	s.mutex.Lock()
+	tracker, ok := s.trackers[c]
+
+	if !ok {
+		tracker = NewTracker(now)
+		s.trackers[c] = tracker
+	}
+
+	var player types.Player
+	var verdict Verdict
+
+	c.ViewPlayer(func(p *types.Player) {
+		player = *p
+		tracker.Record(p.Vehicles, now)
+	})
+
+	tracker.Prune(now.Add(-settings.MaxTimeTime))
+
+	if !player.Permissions.BypassIdle {
+		verdict = tracker.Check(now, settings.MaxTimeTime, float64(settings.MinDistance), lead)
+	}
+
+	if verdict == VerdictKick {
+		delete(s.trackers, c)
+	}
+	s.mutex.Unlock()
+
+	switch verdict {
+	case VerdictWarn:
+		s.Infof("Warning %s for being idle", player.DisplayName)
+		s.warn(player, fmt.Sprintf("You will be kicked for being idle in %s unless you move", lead.Round(time.Second)))
+	case VerdictKick:
+		s.Infof("Kicking %s for being idle for %s", player.DisplayName, settings.MaxTimeTime)
+		c.KickWithBan(KickReason, config.Configuration.Auth.Kick.IdleDurationTime, "Idle")
+	}
+}
+
+// Send a warning to a player through chat
+func (s *IdleService) warn(player types.Player, text string) {
+	if cs, ok := types.App.GetService("Chat").(*chat.ChatService); ok {
+		if err := cs.SendDirectMessage(player.PlayerId, text); err != nil {
+			s.Debugf("Unable to warn %s - %s", player.DisplayName, err.Error())
+		}
+	}
+}
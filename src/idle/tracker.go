@@ -0,0 +1,121 @@
+package idle
+
+import (
+	"math"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// The outcome of checking whether a player is idle
+type Verdict int
+
+const (
+	VerdictActive Verdict = iota // The player has moved far enough
+	VerdictWarn                  // The player will be kicked soon unless they move
+	VerdictKick                  // The player has been idle for too long
+)
+
+// The distance covered between two consecutive samples
+type sample struct {
+	at    time.Time // When the sample was taken
+	moved float64   // How far the vehicles moved since the previous sample, in meters
+}
+
+// Tracker records how far the vehicles of a player have moved over a sliding window
+type Tracker struct {
+	since   time.Time          // When tracking started
+	last    map[int][3]float64 // The last sampled position of each vehicle, by vehicle ID
+	samples []sample           // The movement between consecutive samples, oldest first
+	warned  bool               // Whether the player has been warned since they were last active
+}
+
+// Create a new tracker for a player who has just started playing
+func NewTracker(now time.Time) *Tracker {
+	return &Tracker{
+		since: now,
+		last:  make(map[int][3]float64),
+	}
+}
+
+// Record the current positions of the vehicles of a player.
+// Vehicles which have not sent a position yet are ignored, and new vehicles start without any movement.
+func (t *Tracker) Record(vehicles []*types.Vehicle, now time.Time) {
+	moved := 0.0
+	seen := make(map[int]bool, len(vehicles))
+
+	for _, v := range vehicles {
+		if v.MovedAt.IsZero() {
+			continue
+		}
+
+		if last, ok := t.last[v.Id]; ok {
+			moved += distance(last, v.Position)
+		}
+
+		t.last[v.Id] = v.Position
+		seen[v.Id] = true
+	}
+
+	// Forget vehicles which have been deleted
+	for id := range t.last {
+		if !seen[id] {
+			delete(t.last, id)
+		}
+	}
+
+	t.samples = append(t.samples, sample{at: now, moved: moved})
+}
+
+// Prune drops the samples taken before the start of the window
+func (t *Tracker) Prune(start time.Time) {
+	i := 0
+
+	for i < len(t.samples) && !t.samples[i].at.After(start) {
+		i++
+	}
+
+	t.samples = t.samples[i:]
+}
+
+// Moved returns the distance covered since a point in time
+func (t *Tracker) Moved(start time.Time) float64 {
+	moved := 0.0
+
+	for _, s := range t.samples {
+		if s.at.After(start) {
+			moved += s.moved
+		}
+	}
+
+	return moved
+}
+
+// Check whether the player has covered the minimum distance within the window.
+// Players are warned once, the lead time before they would be kicked.
+func (t *Tracker) Check(now time.Time, window time.Duration, minDistance float64, lead time.Duration) Verdict {
+	tracked := now.Sub(t.since)
+
+	if tracked >= window && t.Moved(now.Add(-window)) < minDistance {
+		return VerdictKick
+	}
+
+	// If the player stays where they are for the lead time, the window will have the same movement as this part of it
+	if remaining := window - lead; tracked >= remaining && t.Moved(now.Add(-remaining)) < minDistance {
+		if t.warned {
+			return VerdictActive
+		}
+
+		t.warned = true
+		return VerdictWarn
+	}
+
+	t.warned = false
+	return VerdictActive
+}
+
+// Get the distance between two positions
+func distance(a [3]float64, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
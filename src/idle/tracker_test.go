@@ -0,0 +1,71 @@
+package idle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// Build a vehicle which has reported a position
+func vehicleAt(id int, x float64, now time.Time) *types.Vehicle {
+	return &types.Vehicle{Id: id, Position: [3]float64{x, 0, 0}, MovedAt: now}
+}
+
+func TestTrackerRecord(t *testing.T) {
+	start := time.Unix(1000, 0)
+	tracker := NewTracker(start)
+
+	tracker.Record([]*types.Vehicle{vehicleAt(0, 0, start), {Id: 1}}, start)
+	tracker.Record([]*types.Vehicle{vehicleAt(0, 30, start)}, start.Add(5*time.Second))
+	tracker.Record([]*types.Vehicle{vehicleAt(0, 30, start), vehicleAt(2, 500, start)}, start.Add(10*time.Second))
+	tracker.Record([]*types.Vehicle{vehicleAt(2, 520, start)}, start.Add(15*time.Second))
+
+	if moved := tracker.Moved(start); moved != 50 {
+		t.Errorf("Expected 50m of movement, got %f", moved)
+	}
+
+	tracker.Prune(start.Add(5 * time.Second))
+
+	if moved := tracker.Moved(start); moved != 20 {
+		t.Errorf("Expected 20m of movement after pruning, got %f", moved)
+	}
+}
+
+func TestTrackerCheck(t *testing.T) {
+	start := time.Unix(1000, 0)
+	window := 10 * time.Minute
+	lead := time.Minute
+	tracker := NewTracker(start)
+
+	for i := 0; i <= 10; i++ {
+		now := start.Add(time.Duration(i) * time.Minute)
+		tracker.Record([]*types.Vehicle{vehicleAt(0, 5, now)}, now)
+
+		verdict := tracker.Check(now, window, 100, lead)
+
+		switch {
+		case i < 9 && verdict != VerdictActive:
+			t.Errorf("Expected the player to be active after %d minutes, got %d", i, verdict)
+		case i == 9 && verdict != VerdictWarn:
+			t.Errorf("Expected the player to be warned after 9 minutes, got %d", verdict)
+		case i == 10 && verdict != VerdictKick:
+			t.Errorf("Expected the player to be kicked after 10 minutes, got %d", verdict)
+		}
+	}
+}
+
+func TestTrackerCheckActive(t *testing.T) {
+	start := time.Unix(1000, 0)
+	tracker := NewTracker(start)
+
+	for i := 0; i <= 20; i++ {
+		now := start.Add(time.Duration(i) * time.Minute)
+		tracker.Record([]*types.Vehicle{vehicleAt(0, float64(i*50), now)}, now)
+		tracker.Prune(now.Add(-10 * time.Minute))
+
+		if verdict := tracker.Check(now, 10*time.Minute, 100, time.Minute); verdict != VerdictActive {
+			t.Fatalf("Expected a moving player to stay active, got %d after %d minutes", verdict, i)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/valkey-io/valkey-go"
 )
@@ -66,3 +67,47 @@ func (c *KeyvalClient) Get(key string) valkey.ValkeyResult {
 		c.client.B().Get().Key(key).Build(),
 	)
 }
+
+// SetUntil sets a key which is removed at the given time
+func (c *KeyvalClient) SetUntil(key string, value string, expires time.Time) valkey.ValkeyResult {
+	return c.client.Do(
+		context.Background(),
+		c.client.B().Set().Key(key).Value(value).Pxat(expires).Build(),
+	)
+}
+
+func (c *KeyvalClient) Del(key string) valkey.ValkeyResult {
+	return c.client.Do(
+		context.Background(),
+		c.client.B().Del().Key(key).Build(),
+	)
+}
+
+// Scan returns every key matching a pattern
+func (c *KeyvalClient) Scan(pattern string) ([]string, error) {
+	keys := []string{}
+	cursor := uint64(0)
+
+	for {
+		entry, err := c.client.Do(
+			context.Background(),
+			c.client.B().Scan().Cursor(cursor).Match(pattern).Count(100).Build(),
+		).AsScanEntry()
+
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, entry.Elements...)
+		cursor = entry.Cursor
+
+		if cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+// IsNil reports whether an error means that a key does not exist
+func IsNil(err error) bool {
+	return valkey.IsValkeyNil(err)
+}
@@ -0,0 +1,382 @@
+package mods
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/crypto"
+	"github.com/altriusrs/netbeams/src/protocol"
+	"github.com/altriusrs/netbeams/src/types"
+	"github.com/fsnotify/fsnotify"
+)
+
+// How long the client folder must be quiet before the manifest is rebuilt.
+// Copying a large mod into the folder produces many events, which should only cause one rebuild.
+const RefreshDelay = 2 * time.Second
+
+var ErrModNotFound = errors.New("mod not found")
+var ErrModChanged = errors.New("mod has changed since the manifest was built")
+
+// A single client mod which is sent to players when they join
+type Mod struct {
+	Name string // The name of the mod, as sent to the client (eg. /mod.zip)
+	Path string // The path to the mod on disk
+	Size int64  // The size of the mod in bytes
+	Hash string // The quick hash of the mod file, used to detect changes
+	Sum  string // The SHA-256 hash of the contents of the mod file, used to verify its integrity
+
+	modified time.Time // When the mod file was last modified
+}
+
+// A ChangeHandler is called with the mods which were added to and removed from the manifest.
+// A mod which has changed appears in both.
+type ChangeHandler func(added []Mod, removed []Mod)
+
+// A Mod Manager service instance, which builds the manifest of client mods and serves them to players
+type ModManager struct {
+	types.Service
+	folder   string            // The folder client mods are loaded from
+	watcher  *fsnotify.Watcher // Watches the client folder for changes
+	mutex    sync.RWMutex      // Guards the manifest and handlers
+	manifest []Mod             // The mods in the client folder, sorted by name
+	byName   map[string]Mod    // The mods in the manifest, keyed by name
+	handlers []ChangeHandler   // Called when the manifest changes
+	loaded   bool              // Whether the manifest has been built at least once
+	delay    time.Duration     // How long the client folder must be quiet before the manifest is rebuilt
+	refresh  chan struct{}     // Signals that the client folder has changed
+	done     chan struct{}     // Closed when the service stops
+}
+
+// Create a new Mod Manager service instance
+func Service() *ModManager {
+	mm := &ModManager{
+		Service: types.SpinUp("Mod Manager"),
+		byName:  make(map[string]Mod),
+		delay:   RefreshDelay,
+		refresh: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+
+	mm.RegisterServiceHooks(mm.Start, mm.Stop, nil)
+
+	return mm
+}
+
+func (s *ModManager) Start() (types.Status, error) {
+	s.folder = filepath.Join(config.Configuration.General.ResourceFolder, "Client")
+
+	if err := os.MkdirAll(s.folder, 0755); err != nil {
+		s.Error("Unable to create the client resource folder - Additional output below")
+		return types.StatusErrored, err
+	}
+
+	if err := s.Refresh(); err != nil {
+		s.Error("Unable to build the mod manifest - Additional output below")
+		return types.StatusErrored, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		s.Error("Unable to watch the client resource folder - Additional output below")
+		return types.StatusErrored, err
+	}
+
+	if err = watcher.Add(s.folder); err != nil {
+		_ = watcher.Close()
+		s.Error("Unable to watch the client resource folder - Additional output below")
+		return types.StatusErrored, err
+	}
+
+	s.watcher = watcher
+
+	go s.Watch()
+	go s.RefreshLoop()
+
+	return types.StatusHealthy, nil
+}
+
+func (s *ModManager) Stop() (types.Status, error) {
+	close(s.done)
+
+	if s.watcher != nil {
+		_ = s.watcher.Close()
+	}
+
+	return types.StatusShutdown, nil
+}
+
+// Watch the client folder, requesting a refresh of the manifest whenever a mod changes
+func (s *ModManager) Watch() {
+	for {
+		select {
+		case event, ok := <-s.watcher.Events:
+			if !ok {
+				return
+			}
+
+			if !strings.EqualFold(filepath.Ext(event.Name), ".zip") {
+				continue
+			}
+
+			s.Debugf("Mod file %s changed (%s)", event.Name, event.Op)
+
+			select {
+			case s.refresh <- struct{}{}:
+			default:
+			}
+		case err, ok := <-s.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			s.Error(err.Error())
+		}
+	}
+}
+
+// RefreshLoop rebuilds the manifest once the client folder has stopped changing
+func (s *ModManager) RefreshLoop() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.refresh:
+		}
+
+		// Wait for the folder to settle, restarting the delay on every change
+		timer := time.NewTimer(s.delay)
+
+	settle:
+		for {
+			select {
+			case <-s.done:
+				timer.Stop()
+				return
+			case <-s.refresh:
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(s.delay)
+			case <-timer.C:
+				break settle
+			}
+		}
+
+		if err := s.Refresh(); err != nil {
+			s.Error("Unable to rebuild the mod manifest - Additional output below")
+			s.Error(err.Error())
+		}
+	}
+}
+
+// OnChange registers a handler which is called whenever the manifest changes
+func (s *ModManager) OnChange(handler ChangeHandler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.handlers = append(s.handlers, handler)
+}
+
+// Refresh rebuilds the manifest from the files in the client resource folder.
+// Hashes are only recalculated for mods which have changed since the last refresh.
+func (s *ModManager) Refresh() error {
+	entries, err := os.ReadDir(s.folder)
+
+	if err != nil {
+		return err
+	}
+
+	s.mutex.RLock()
+	previous := s.byName
+	s.mutex.RUnlock()
+
+	manifest := []Mod{}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".zip") {
+			continue
+		}
+
+		info, err := entry.Info()
+
+		if err != nil {
+			s.Warnf("Skipping mod %s - %s", entry.Name(), err.Error())
+			continue
+		}
+
+		name := "/" + entry.Name()
+
+		if cached, ok := previous[name]; ok && cached.Size == info.Size() && cached.modified.Equal(info.ModTime()) {
+			manifest = append(manifest, cached)
+			continue
+		}
+
+		mod, err := s.load(name, filepath.Join(s.folder, entry.Name()), info)
+
+		if err != nil {
+			s.Warnf("Skipping mod %s - %s", entry.Name(), err.Error())
+			continue
+		}
+
+		manifest = append(manifest, mod)
+	}
+
+	sort.Slice(manifest, func(i, j int) bool {
+		return manifest[i].Name < manifest[j].Name
+	})
+
+	byName := make(map[string]Mod, len(manifest))
+	for _, mod := range manifest {
+		byName[mod.Name] = mod
+	}
+
+	added, removed := diff(previous, byName)
+
+	s.mutex.Lock()
+	seeded := !s.loaded
+	s.loaded = true
+	s.manifest = manifest
+	s.byName = byName
+	handlers := append([]ChangeHandler{}, s.handlers...)
+	s.mutex.Unlock()
+
+	// The first refresh only seeds the manifest, so there is nothing to announce to players
+	if seeded {
+		s.Infof("Loaded %d client mods", len(manifest))
+		return nil
+	}
+
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	s.Infof("Loaded %d client mods (%d added, %d removed)", len(manifest), len(added), len(removed))
+
+	for _, handler := range handlers {
+		handler(added, removed)
+	}
+
+	return nil
+}
+
+// Hash a mod file and create its manifest entry
+func (s *ModManager) load(name string, path string, info os.FileInfo) (Mod, error) {
+	hash, err := crypto.HashFile(path)
+
+	if err != nil {
+		return Mod{}, err
+	}
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		return Mod{}, err
+	}
+
+	defer file.Close()
+
+	hasher := sha256.New()
+
+	if _, err = io.Copy(hasher, file); err != nil {
+		return Mod{}, err
+	}
+
+	s.Debugf("Hashed mod %s", name)
+
+	return Mod{
+		Name:     name,
+		Path:     path,
+		Size:     info.Size(),
+		Hash:     *hash,
+		Sum:      crypto.EncodeToHex(hasher.Sum(nil)),
+		modified: info.ModTime(),
+	}, nil
+}
+
+// Find the mods which were added and removed between two manifests
+func diff(previous map[string]Mod, current map[string]Mod) ([]Mod, []Mod) {
+	added := []Mod{}
+	removed := []Mod{}
+
+	for name, mod := range current {
+		if old, ok := previous[name]; !ok || old.Sum != mod.Sum {
+			added = append(added, mod)
+		}
+	}
+
+	for name, mod := range previous {
+		if now, ok := current[name]; !ok || now.Sum != mod.Sum {
+			removed = append(removed, mod)
+		}
+	}
+
+	return added, removed
+}
+
+// Manifest returns the mods which are sent to players
+func (s *ModManager) Manifest() []Mod {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return append([]Mod{}, s.manifest...)
+}
+
+// ModList returns the manifest in the form sent to the client
+func (s *ModManager) ModList() protocol.ModList {
+	list := protocol.ModList{}
+
+	for _, mod := range s.Manifest() {
+		list.Files = append(list.Files, protocol.ModFile{Name: mod.Name, Size: mod.Size})
+	}
+
+	return list
+}
+
+// Lookup a mod in the manifest by the name requested by the client
+func (s *ModManager) Lookup(name string) (Mod, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	mod, ok := s.byName[name]
+
+	return mod, ok
+}
+
+// Open a mod for sending to the client, checking it still matches the manifest
+func (s *ModManager) Open(name string) (*os.File, Mod, error) {
+	mod, ok := s.Lookup(name)
+
+	if !ok {
+		return nil, Mod{}, ErrModNotFound
+	}
+
+	file, err := os.Open(mod.Path)
+
+	if err != nil {
+		return nil, mod, err
+	}
+
+	info, err := file.Stat()
+
+	if err != nil {
+		_ = file.Close()
+		return nil, mod, err
+	}
+
+	// The client has already been told the size of the file, so it must not have changed
+	if info.Size() != mod.Size || !info.ModTime().Equal(mod.modified) {
+		_ = file.Close()
+		return nil, mod, ErrModChanged
+	}
+
+	return file, mod, nil
+}
@@ -0,0 +1,196 @@
+package mods
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// A change reported to a handler
+type change struct {
+	added   []string
+	removed []string
+}
+
+// Create a mod manager for a temporary client folder, without watching it
+func newTestManager(t *testing.T) *ModManager {
+	t.Helper()
+
+	mm := Service()
+	mm.folder = t.TempDir()
+
+	return mm
+}
+
+// Write a mod file to the client folder
+func writeMod(t *testing.T, mm *ModManager, name string, contents string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(mm.folder, name), []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Record every change reported by the mod manager
+func recordChanges(mm *ModManager) chan change {
+	changes := make(chan change, 8)
+
+	mm.OnChange(func(added []Mod, removed []Mod) {
+		changes <- change{added: names(added), removed: names(removed)}
+	})
+
+	return changes
+}
+
+// The sorted names of a list of mods
+func names(mods []Mod) []string {
+	list := []string{}
+
+	for _, mod := range mods {
+		list = append(list, mod.Name)
+	}
+
+	sort.Strings(list)
+
+	return list
+}
+
+func equal(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestDiff(t *testing.T) {
+	previous := map[string]Mod{
+		"/kept.zip":    {Name: "/kept.zip", Sum: "1"},
+		"/changed.zip": {Name: "/changed.zip", Sum: "2"},
+		"/removed.zip": {Name: "/removed.zip", Sum: "3"},
+	}
+
+	current := map[string]Mod{
+		"/kept.zip":    {Name: "/kept.zip", Sum: "1"},
+		"/changed.zip": {Name: "/changed.zip", Sum: "4"},
+		"/added.zip":   {Name: "/added.zip", Sum: "5"},
+	}
+
+	added, removed := diff(previous, current)
+
+	if got, expected := names(added), []string{"/added.zip", "/changed.zip"}; !equal(got, expected) {
+		t.Errorf("Expected %v to be added, got %v", expected, got)
+	}
+
+	if got, expected := names(removed), []string{"/changed.zip", "/removed.zip"}; !equal(got, expected) {
+		t.Errorf("Expected %v to be removed, got %v", expected, got)
+	}
+}
+
+func TestRefreshSeedsWithoutNotifying(t *testing.T) {
+	mm := newTestManager(t)
+	changes := recordChanges(mm)
+
+	writeMod(t, mm, "a.zip", "a")
+	writeMod(t, mm, "notes.txt", "not a mod")
+
+	if err := mm.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case c := <-changes:
+		t.Errorf("Expected the first refresh not to announce any changes, got %+v", c)
+	default:
+	}
+
+	if got := names(mm.Manifest()); !equal(got, []string{"/a.zip"}) {
+		t.Errorf("Expected the manifest to contain /a.zip, got %v", got)
+	}
+}
+
+func TestRefreshReportsChanges(t *testing.T) {
+	mm := newTestManager(t)
+	changes := recordChanges(mm)
+
+	writeMod(t, mm, "a.zip", "a")
+	writeMod(t, mm, "b.zip", "b")
+
+	if err := mm.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeMod(t, mm, "a.zip", "changed")
+	writeMod(t, mm, "c.zip", "c")
+
+	if err := os.Remove(filepath.Join(mm.folder, "b.zip")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mm.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case c := <-changes:
+		if !equal(c.added, []string{"/a.zip", "/c.zip"}) || !equal(c.removed, []string{"/a.zip", "/b.zip"}) {
+			t.Errorf("Expected /a.zip to change, /b.zip to be removed and /c.zip to be added, got %+v", c)
+		}
+	default:
+		t.Fatal("Expected the changes to be announced")
+	}
+
+	// Nothing is announced when the folder has not changed
+	if err := mm.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case c := <-changes:
+		t.Errorf("Expected no changes to be announced, got %+v", c)
+	default:
+	}
+}
+
+func TestRefreshLoopDebounces(t *testing.T) {
+	mm := newTestManager(t)
+	mm.delay = 100 * time.Millisecond
+	changes := recordChanges(mm)
+
+	if err := mm.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	go mm.RefreshLoop()
+	defer close(mm.done)
+
+	// A burst of events restarts the delay, so the manifest is only rebuilt once
+	for _, name := range []string{"a.zip", "b.zip", "c.zip"} {
+		writeMod(t, mm, name, name)
+		mm.refresh <- struct{}{}
+		time.Sleep(mm.delay / 4)
+	}
+
+	select {
+	case c := <-changes:
+		if !equal(c.added, []string{"/a.zip", "/b.zip", "/c.zip"}) {
+			t.Errorf("Expected every mod to be added at once, got %+v", c)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the manifest to be rebuilt")
+	}
+
+	select {
+	case c := <-changes:
+		t.Errorf("Expected a single rebuild, got another %+v", c)
+	case <-time.After(2 * mm.delay):
+	}
+}
@@ -2,18 +2,45 @@ package player_manager
 
 import (
 	"fmt"
-
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/altriusrs/netbeams/src/config"
 	"github.com/altriusrs/netbeams/src/types"
 )
 
+// How often reservations and online time are checked
+const ReservatorInterval = 5 * time.Second
+
+// How often online time is saved while players are playing
+const UsageSaveInterval = time.Minute
+
+// Players are warned when this much online time remains
+var QuotaWarnings = []time.Duration{10 * time.Minute, 5 * time.Minute, time.Minute}
+
+// A QuotaHandler is called when a player is running out of online time, and with no time remaining once it has run out
+type QuotaHandler func(id int, remaining time.Duration)
+
+// The online time of a player who is playing
+type session struct {
+	key    string        // The account the time is recorded against
+	start  time.Time     // When the player started playing
+	used   time.Duration // The online time the account had used before this session
+	warned int           // The number of quota warnings sent to the player
+}
+
 // A Player Manager service instance
 type PlayerManager struct {
 	types.Service
+	mutex        sync.RWMutex // Guards the players, reservations and sessions
 	Players      map[int]*types.Player
 	Reservations map[int]time.Time
+
+	sessions  map[int]*session // The online time of players with a quota, by player ID
+	usage     *Usage           // The online time used by each account
+	lastSaved time.Time        // When the online time was last saved by the reservator
+	handlers  []QuotaHandler   // Called when players are running out of online time
 }
 
 // Create a new Player Manager service instance
@@ -22,6 +49,8 @@ func Service() *PlayerManager {
 		Service:      types.SpinUp("Player Manager"),
 		Players:      make(map[int]*types.Player),
 		Reservations: make(map[int]time.Time),
+		sessions:     make(map[int]*session),
+		usage:        newUsage(""),
 	}
 
 	pm.RegisterServiceHooks(pm.StartHook, pm.ShutdownHook, nil)
@@ -35,6 +64,16 @@ func (s *PlayerManager) ShutdownHook() (types.Status, error) {
 
 	// TODO: Close connections and remove players from the service
 
+	// Record the online time of everyone still playing, so that it is not lost over a restart
+	s.mutex.Lock()
+	now := time.Now()
+	for _, sess := range s.sessions {
+		s.usage.Set(sess.key, sess.used+now.Sub(sess.start))
+	}
+	s.mutex.Unlock()
+
+	s.saveUsage()
+
 	return types.StatusShutdown, nil
 }
 
@@ -42,72 +81,171 @@ func (s *PlayerManager) ShutdownHook() (types.Status, error) {
 func (s *PlayerManager) StartHook() (types.Status, error) {
 	s.Info("Starting Player Manager service")
 
+	if online := config.Configuration.Auth.Online; online.Enable {
+		usage, err := LoadUsage(online.UsageFile)
+
+		if err != nil {
+			s.Error("Error loading online usage - Additional output below")
+			s.Error(err.Error())
+		}
+
+		s.usage = usage
+	}
+
 	// start the reservation manager
 	go s.Reservator()
 
 	return types.StatusHealthy, nil
 }
 
+// OnQuota registers a handler which is called when players are running out of online time
+func (s *PlayerManager) OnQuota(handler QuotaHandler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.handlers = append(s.handlers, handler)
+}
+
+// Reservator releases expired reservations and enforces the online time quota, until the service stops
 func (s *PlayerManager) Reservator() {
-	for *s.Status == types.StatusHealthy || *s.Status == types.StatusStarting {
-		time.Sleep(time.Second * 60)
+	ticker := time.NewTicker(ReservatorInterval)
+	defer ticker.Stop()
 
-		s.Info("Managing reservations")
+	for now := range ticker.C {
+		if *s.Status != types.StatusHealthy && *s.Status != types.StatusStarting {
+			return
+		}
 
-		for id, t := range s.Reservations {
-			if t.IsZero() {
-				continue // Skip if the time is equivalent to EPOCH (zero time)
-			}
+		s.manageReservations(now)
 
-			// Check if the time is in the past
-			if t.Before(time.Now()) {
-				delete(s.Reservations, id) // Remove the reservation to free the slot
-				s.Players[id] = nil        // Set the player element to nil so that it can be avoided by other methods
-				continue
-			}
+		if now.Sub(s.lastSaved) >= UsageSaveInterval {
+			s.lastSaved = now
+			s.saveUsage()
+		}
+	}
+}
 
-			// Check if the time is in the future
-			if t.After(time.Now()) {
+// A quota event waiting to be sent to the handlers
+type quotaNotice struct {
+	id        int
+	remaining time.Duration
+}
 
-			}
+// Release expired reservations, and warn or expire players who are running out of online time
+func (s *PlayerManager) manageReservations(now time.Time) {
+	notices := []quotaNotice{}
 
-			if time.Until(t) < time.Minute*10 {
-				// types.App.GetService("TCP Server").(*tcp.Server)
-			} else if time.Until(t) < time.Minute*5 {
-				// types.App.GetService("TCP Server").(*tcp.Server)
-			} else if time.Until(t) < time.Minute*1 {
-				// types.App.GetService("TCP Server").(*tcp.Server)
-			} else if time.Until(t) < time.Second*1 {
-				// types.App.GetService("TCP Server").(*tcp.Server).KickPlayer(id)
+	s.mutex.Lock()
+	for id, t := range s.Reservations {
+		if t.IsZero() {
+			continue // Skip if the time is equivalent to EPOCH (zero time)
+		}
+
+		sess, playing := s.sessions[id]
+
+		// Slots reserved for joining players are released if they do not finish joining in time
+		if !playing {
+			if t.Before(now) {
 				delete(s.Reservations, id) // Remove the reservation to free the slot
-				s.Players[id] = nil        // Set the player element to nil so that it can be avoided by other methods
-			} else {
-				s.Debugf("Player %s (%d) has %f minutes left to play", s.Players[id].Account.Name, id, time.Until(t).Minutes())
+				delete(s.Players, id)      // Remove the player so that it is avoided by other methods
 			}
+			continue
+		}
+
+		s.usage.Set(sess.key, sess.used+now.Sub(sess.start))
+		remaining := t.Sub(now)
+
+		// The slot is released when the player is kicked
+		if remaining <= 0 {
+			s.Infof("Player %d has run out of online time", id)
+			s.usage.Expire(sess.key)
+			s.Reservations[id] = time.Time{}
+			delete(s.sessions, id)
+			notices = append(notices, quotaNotice{id: id, remaining: 0})
+			continue
+		}
+
+		warned := sess.warned
+		for sess.warned < len(QuotaWarnings) && remaining <= QuotaWarnings[sess.warned] {
+			sess.warned++
+		}
+
+		if sess.warned > warned {
+			notices = append(notices, quotaNotice{id: id, remaining: remaining})
+		} else {
+			s.Debugf("Player %d has %.1f minutes left to play", id, remaining.Minutes())
+		}
+	}
+	handlers := s.handlers
+	s.mutex.Unlock()
+
+	for _, n := range notices {
+		for _, handler := range handlers {
+			handler(n.id, n.remaining)
 		}
 	}
 }
 
-// Add a new player to the service
+// Save the online time used by each account
+func (s *PlayerManager) saveUsage() {
+	if err := s.usage.Save(); err != nil {
+		s.Error("Error saving online usage - Additional output below")
+		s.Error(err.Error())
+	}
+}
+
+// Add a new player to a reserved slot.
+// The service keeps its own copy of the player, as the connection goes on to change the original while it plays.
 func (s *PlayerManager) AddPlayer(player *types.Player, id int) (*int, error) {
 	s.Infof("Adding player %s (%s)", player.Account.Name, player.Account.Id)
 
-	pid, err := s.ReserveSlotForPlay(id)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-	if err != nil {
-		return nil, err
+	if _, ok := s.Reservations[id]; !ok {
+		return nil, fmt.Errorf("cannot add player to non-reserved slot")
 	}
 
-	s.Players[*pid] = player
+	entry := *player
+	entry.Vehicles = nil
+	s.Players[id] = &entry
+
 	// Reserve the slot for 5 minutes
 	// This is to allow the player to connect and load mods, without concern of disconnecting
-	s.Reservations[*pid] = time.Now().Add(time.Minute * 5)
+	s.Reservations[id] = time.Now().Add(time.Minute * 5)
+
+	return &id, nil
+}
+
+// Remove a player from the service, releasing their slot
+func (s *PlayerManager) RemovePlayer(id int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if player, ok := s.Players[id]; ok && player.Account != nil {
+		s.Infof("Removing player %s (%s)", player.Account.Name, player.Account.Id)
+	}
 
-	return pid, nil
+	// Keep the online time the player used, for when they rejoin
+	if sess, ok := s.sessions[id]; ok {
+		s.usage.Set(sess.key, sess.used+time.Since(sess.start))
+		delete(s.sessions, id)
+	}
+
+	delete(s.Players, id)
+	delete(s.Reservations, id)
 }
 
 // Get the next available player ID
 func (s *PlayerManager) GetNextID() (*int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.nextID()
+}
+
+// Get the next available player ID (the caller must hold the lock)
+func (s *PlayerManager) nextID() (*int, error) {
 
 	// Get the total number of players
 	total := len(s.Reservations)
@@ -118,7 +256,7 @@ func (s *PlayerManager) GetNextID() (*int, error) {
 	}
 
 	// Get the next available ID
-	for id := range config.Configuration.General.MaxPlayers {
+	for id := 0; id < config.Configuration.General.MaxPlayers; id++ {
 
 		if _, ok := s.Reservations[id]; !ok {
 			return &id, nil
@@ -134,6 +272,9 @@ func (s *PlayerManager) GetNextID() (*int, error) {
 // reached the "playing" state within that time.
 // It may be reserved once again if the server has mods to synchronize, this reservation is valid for 5 minutes
 func (s *PlayerManager) ReserveSlotForConnection(id *int) (*int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	if id != nil {
 		if _, ok := s.Reservations[*id]; !ok {
 			s.Reservations[*id] = time.Now().Add(time.Second * 60)
@@ -141,7 +282,7 @@ func (s *PlayerManager) ReserveSlotForConnection(id *int) (*int, error) {
 		}
 	}
 
-	id, err := s.GetNextID()
+	id, err := s.nextID()
 
 	if err != nil {
 		return nil, err
@@ -158,42 +299,95 @@ func (s *PlayerManager) ReserveSlotForConnection(id *int) (*int, error) {
 
 // Reserve a slot for a loading period
 func (s *PlayerManager) ReserveSlotForLoad(id int) (*int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	if _, ok := s.Reservations[id]; ok {
+		idle := config.Configuration.Auth.Idle
 
-		if config.Configuration.Auth.Idle.Enable {
-			s.Reservations[id] = time.Now().Add(time.Minute * config.Configuration.Auth.Idle.MaxTimeTime)
+		if idle.Enable && idle.MaxTimeTime > 0 {
+			s.Reservations[id] = time.Now().Add(idle.MaxTimeTime)
 		} else {
 			s.Reservations[id] = time.Time{}
 		}
 
-		s.Reservations[id] = time.Now().Add(time.Minute * config.Configuration.Auth.Idle.MaxTimeTime)
 		return &id, nil
 	}
 
 	return nil, fmt.Errorf("cannot reserve slot for non-reserved player")
 }
 
-// Reserve a slot for play duration
+// Reserve a slot for play duration.
+// If the online quota is enabled, the reservation expires when the player runs out of online time.
 func (s *PlayerManager) ReserveSlotForPlay(id int) (*int, error) {
-	if _, ok := s.Reservations[id]; ok {
-		if config.Configuration.Auth.Idle.Enable {
-			s.Reservations[id] = time.Now().Add(time.Minute * config.Configuration.Auth.Online.QuotaTime)
-		} else {
-			s.Reservations[id] = time.Time{}
-		}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.Reservations[id]; !ok {
+		return nil, fmt.Errorf("cannot reserve slot for non-reserved player")
+	}
+
+	s.Reservations[id] = time.Time{}
+
+	online := config.Configuration.Auth.Online
+	player, ok := s.Players[id]
+
+	if !online.Enable || online.QuotaTime <= 0 || !ok || player.Account == nil || player.Permissions.BypassOnline {
 		return &id, nil
 	}
 
-	return nil, fmt.Errorf("cannot reserve slot for non-reserved player")
+	now := time.Now()
+	key := player.Account.Key()
+	used := s.usage.Used(key)
+
+	s.sessions[id] = &session{key: key, start: now, used: used}
+	s.Reservations[id] = now.Add(online.QuotaTime - used)
+
+	s.Debugf("Player %d has %.1f minutes of online time left", id, (online.QuotaTime - used).Minutes())
+
+	return &id, nil
 }
 
 // Get a player by their ID
 func (s *PlayerManager) GetPlayer(id int) *types.Player {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	return s.Players[id]
 }
 
+// Get every player on the server, ordered by their ID
+func (s *PlayerManager) GetPlayers() []*types.Player {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ids := make([]int, 0, len(s.Players))
+	for id := range s.Players {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	players := make([]*types.Player, 0, len(ids))
+	for _, id := range ids {
+		players = append(players, s.Players[id])
+	}
+
+	return players
+}
+
+// Get the number of players on the server
+func (s *PlayerManager) Count() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return len(s.Players)
+}
+
 // Get a player by their public key
 func (s *PlayerManager) GetPlayerByPublicKey(key string) *types.Player {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	for _, p := range s.Players {
 		if p.PublicKey == key {
 			return p
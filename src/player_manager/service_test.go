@@ -0,0 +1,206 @@
+package player_manager
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+func TestUsageSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+
+	usage, err := LoadUsage(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usage.Set("player", 42*time.Minute)
+	usage.Set("expired", time.Hour)
+	usage.Expire("expired")
+
+	if err := usage.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadUsage(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if used := loaded.Used("player"); used != 42*time.Minute {
+		t.Errorf("Expected 42 minutes of usage to be loaded, got %s", used)
+	}
+
+	if used := loaded.Used("expired"); used != 0 {
+		t.Errorf("Expected expired usage to be reset, got %s", used)
+	}
+}
+
+func TestQuotaWarningsAndExpiry(t *testing.T) {
+	config.Configuration.General.MaxPlayers = 4
+	config.Configuration.Auth.Online.Enable = true
+	config.Configuration.Auth.Online.QuotaTime = 11 * time.Minute
+
+	pm := Service()
+	account := &types.Account{Name: "driver", Id: "abc"}
+
+	// The player has already used a minute of their quota
+	pm.usage.Set(account.Key(), time.Minute)
+
+	id, err := pm.ReserveSlotForConnection(nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = pm.AddPlayer(&types.Player{Account: account}, *id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = pm.ReserveSlotForPlay(*id); err != nil {
+		t.Fatal(err)
+	}
+
+	notices := []time.Duration{}
+	pm.OnQuota(func(_ int, remaining time.Duration) {
+		notices = append(notices, remaining.Round(time.Minute))
+	})
+
+	start := time.Now()
+
+	for _, elapsed := range []time.Duration{time.Minute, 2 * time.Minute, 6 * time.Minute, 7 * time.Minute, 9*time.Minute + 15*time.Second, 11 * time.Minute} {
+		pm.manageReservations(start.Add(elapsed))
+	}
+
+	expected := []time.Duration{9 * time.Minute, 4 * time.Minute, 1 * time.Minute, 0}
+
+	if len(notices) != len(expected) {
+		t.Fatalf("Expected notices %v, got %v", expected, notices)
+	}
+
+	for i := range expected {
+		if notices[i] != expected[i] {
+			t.Errorf("Expected notices %v, got %v", expected, notices)
+			break
+		}
+	}
+
+	if used := pm.usage.Used(account.Key()); used != 0 {
+		t.Errorf("Expected the online time to be reset, got %s", used)
+	}
+}
+
+func TestQuotaBypass(t *testing.T) {
+	config.Configuration.General.MaxPlayers = 4
+	config.Configuration.Auth.Online.Enable = true
+	config.Configuration.Auth.Online.QuotaTime = time.Minute
+
+	pm := Service()
+	player := &types.Player{Account: &types.Account{Name: "admin"}}
+	player.Permissions.BypassOnline = true
+
+	id, _ := pm.ReserveSlotForConnection(nil)
+	_, _ = pm.AddPlayer(player, *id)
+	_, _ = pm.ReserveSlotForPlay(*id)
+
+	if expiry := pm.Reservations[*id]; !expiry.IsZero() {
+		t.Errorf("Expected players who bypass the quota to have no expiry, got %s", expiry)
+	}
+}
+
+func TestAddPlayerKeepsCopy(t *testing.T) {
+	config.Configuration.General.MaxPlayers = 4
+
+	pm := Service()
+	player := &types.Player{DisplayName: "driver", Account: &types.Account{Name: "driver"}}
+
+	id, _ := pm.ReserveSlotForConnection(nil)
+
+	if _, err := pm.AddPlayer(player, *id); err != nil {
+		t.Fatal(err)
+	}
+
+	// The connection keeps changing its own player once it has been added
+	player.DisplayName = "renamed"
+	player.Latency.RTT = time.Second
+
+	stored := pm.GetPlayer(*id)
+
+	if stored == player {
+		t.Fatal("Expected the service to keep its own copy of the player")
+	}
+
+	if stored.DisplayName != "driver" || stored.Latency.RTT != 0 {
+		t.Errorf("Expected the stored player to be unchanged, got %+v", stored)
+	}
+}
+
+func TestAddPlayerRequiresReservation(t *testing.T) {
+	config.Configuration.General.MaxPlayers = 4
+
+	pm := Service()
+
+	if _, err := pm.AddPlayer(&types.Player{Account: &types.Account{Name: "driver"}}, 2); err == nil {
+		t.Error("Expected a player to be refused a slot which was not reserved")
+	}
+
+	if count := len(pm.GetPlayers()); count != 0 {
+		t.Errorf("Expected no players, got %d", count)
+	}
+}
+
+func TestRemovePlayerReleasesSlot(t *testing.T) {
+	config.Configuration.General.MaxPlayers = 1
+
+	pm := Service()
+
+	id, _ := pm.ReserveSlotForConnection(nil)
+	_, _ = pm.AddPlayer(&types.Player{Account: &types.Account{Name: "driver"}}, *id)
+
+	if _, err := pm.ReserveSlotForConnection(nil); err == nil {
+		t.Fatal("Expected the server to be full")
+	}
+
+	pm.RemovePlayer(*id)
+
+	if player := pm.GetPlayer(*id); player != nil {
+		t.Errorf("Expected the player to be removed, got %+v", player)
+	}
+
+	if _, err := pm.ReserveSlotForConnection(nil); err != nil {
+		t.Errorf("Expected the slot to be free again, got %s", err)
+	}
+}
+
+func TestJoiningReservationExpires(t *testing.T) {
+	config.Configuration.General.MaxPlayers = 4
+	config.Configuration.Auth.Online.Enable = false
+
+	pm := Service()
+
+	joining, _ := pm.ReserveSlotForConnection(nil)
+	_, _ = pm.AddPlayer(&types.Player{Account: &types.Account{Name: "joining"}}, *joining)
+
+	playing, _ := pm.ReserveSlotForConnection(nil)
+	_, _ = pm.AddPlayer(&types.Player{Account: &types.Account{Name: "playing"}}, *playing)
+
+	if _, err := pm.ReserveSlotForPlay(*playing); err != nil {
+		t.Fatal(err)
+	}
+
+	// Players who never finish joining lose their slot, players who have loaded keep it
+	pm.manageReservations(time.Now().Add(time.Hour))
+
+	if _, ok := pm.Reservations[*joining]; ok || pm.GetPlayer(*joining) != nil {
+		t.Error("Expected the joining player to lose their slot")
+	}
+
+	if _, ok := pm.Reservations[*playing]; !ok || pm.GetPlayer(*playing) == nil {
+		t.Error("Expected the playing player to keep their slot")
+	}
+}
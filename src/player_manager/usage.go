@@ -0,0 +1,140 @@
+package player_manager
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The online time used by a single account
+type usageEntry struct {
+	Used      time.Duration `json:"used"`       // The online time used since the quota was last reset
+	UpdatedAt time.Time     `json:"updated_at"` // When the entry was last changed
+}
+
+// Usage tracks the online time used by each account, saved to a file so that it survives restarts
+type Usage struct {
+	mutex   sync.Mutex
+	path    string                 // The file the usage is saved to
+	entries map[string]*usageEntry // Entries keyed by account
+	dirty   bool                   // Whether there are changes which have not been saved
+}
+
+// LoadUsage reads the usage saved in a file, starting empty if the file does not exist yet
+func LoadUsage(path string) (*Usage, error) {
+	u := newUsage(path)
+
+	content, err := os.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return u, nil
+	} else if err != nil {
+		return u, err
+	}
+
+	if err = json.Unmarshal(content, &u.entries); err != nil {
+		return u, err
+	}
+
+	return u, nil
+}
+
+// Create an empty usage store, which is saved to the given file (or not saved at all if it is empty)
+func newUsage(path string) *Usage {
+	return &Usage{
+		path:    path,
+		entries: make(map[string]*usageEntry),
+	}
+}
+
+// Used returns the online time an account has used
+func (u *Usage) Used(key string) time.Duration {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if entry, ok := u.entries[key]; ok {
+		return entry.Used
+	}
+
+	return 0
+}
+
+// Set the online time an account has used
+func (u *Usage) Set(key string, used time.Duration) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	entry := u.entry(key)
+	entry.Used = used
+	entry.UpdatedAt = time.Now()
+	u.dirty = true
+}
+
+// Expire resets the online time of an account which has run out
+func (u *Usage) Expire(key string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	entry := u.entry(key)
+	entry.Used = 0
+	entry.UpdatedAt = time.Now()
+	u.dirty = true
+}
+
+// Save writes the usage to its file, if it has changed since it was last saved.
+// The file is replaced in one step, so a crash while saving cannot corrupt it.
+func (u *Usage) Save() error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if !u.dirty || u.path == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(u.entries, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(u.path), filepath.Base(u.path)+".*")
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp.Name(), u.path); err != nil {
+		return err
+	}
+
+	u.dirty = false
+
+	return nil
+}
+
+// Get the entry for an account, creating it if needed (the caller must hold the lock)
+func (u *Usage) entry(key string) *usageEntry {
+	entry, ok := u.entries[key]
+
+	if !ok {
+		entry = &usageEntry{}
+		u.entries[key] = entry
+	}
+
+	return entry
+}
@@ -0,0 +1,64 @@
+package protocol
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Chat is a chat message, sent by the client and relayed by the server
+//
+//	C:<name>: <message>
+type Chat struct {
+	Name    string // The name of the sender
+	Message string // The message sent
+}
+
+func (m *Chat) Encode() []byte {
+	return []byte("C:" + m.Name + ": " + m.Message)
+}
+
+func (m *Chat) Decode(data []byte) error {
+	if len(data) < 4 || string(data[:2]) != "C:" {
+		return malformed("chat", "expected C:<name>: <message>")
+	}
+
+	separator := bytes.IndexByte(data[3:], ':')
+
+	if separator == -1 {
+		return malformed("chat", "missing name separator")
+	}
+
+	separator += 3
+
+	m.Name = string(data[2:separator])
+	m.Message = strings.TrimPrefix(string(data[separator+1:]), " ")
+	return nil
+}
+
+// Event is a custom event, used by client and server side scripts
+//
+//	E:<name>:<data>
+type Event struct {
+	Name string // The name of the event
+	Data string // The data attached to the event
+}
+
+func (m *Event) Encode() []byte {
+	return []byte("E:" + m.Name + ":" + m.Data)
+}
+
+func (m *Event) Decode(data []byte) error {
+	if len(data) < 3 || string(data[:2]) != "E:" {
+		return malformed("event", "expected E:<name>:<data>")
+	}
+
+	parts := strings.SplitN(string(data[2:]), ":", 2)
+
+	if len(parts) != 2 || parts[0] == "" {
+		return malformed("event", "missing event name")
+	}
+
+	m.Name = parts[0]
+	m.Data = parts[1]
+	return nil
+}
@@ -0,0 +1,116 @@
+package protocol
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ServerInfo is sent by the server in response to a P (probe) connection.
+// The fields use the same names and form encoding as the backend heartbeat.
+//
+//	P<form encoded fields>
+type ServerInfo struct {
+	Name          string   `json:"name"`          // The name of the server
+	Map           string   `json:"map"`           // The path to the map info file
+	Players       int      `json:"players"`       // The number of players on the server
+	MaxPlayers    int      `json:"maxplayers"`    // The maximum number of players on the server
+	PlayerList    []string `json:"playerslist"`   // The names of the players on the server
+	Tags          string   `json:"tags"`          // The tags of the server
+	Description   string   `json:"desc"`          // The description of the server
+	Port          int      `json:"port"`          // The port of the server
+	Private       bool     `json:"private"`       // Whether the server is hidden from the server list
+	Guests        bool     `json:"guests"`        // Whether guests may join the server
+	ModsTotal     int      `json:"modstotal"`     // The number of client mods
+	ModsTotalSize int64    `json:"modstotalsize"` // The size of the client mods in bytes
+	Version       string   `json:"version"`       // The version of the server
+	ClientVersion string   `json:"clientversion"` // The minimum client version supported by the server
+}
+
+func (m *ServerInfo) Encode() []byte {
+	values := url.Values{}
+
+	values.Set("name", m.Name)
+	values.Set("map", m.Map)
+	values.Set("players", strconv.Itoa(m.Players))
+	values.Set("maxplayers", strconv.Itoa(m.MaxPlayers))
+	values.Set("playerslist", strings.Join(m.PlayerList, ";"))
+	values.Set("tags", m.Tags)
+	values.Set("desc", m.Description)
+	values.Set("port", strconv.Itoa(m.Port))
+	values.Set("private", strconv.FormatBool(m.Private))
+	values.Set("guests", strconv.FormatBool(m.Guests))
+	values.Set("modstotal", strconv.Itoa(m.ModsTotal))
+	values.Set("modstotalsize", strconv.FormatInt(m.ModsTotalSize, 10))
+	values.Set("version", m.Version)
+	values.Set("clientversion", m.ClientVersion)
+
+	return []byte("P" + values.Encode())
+}
+
+func (m *ServerInfo) Decode(data []byte) error {
+	if len(data) < 1 || data[0] != 'P' {
+		return malformed("server info", "expected P<fields>")
+	}
+
+	values, err := url.ParseQuery(string(data[1:]))
+
+	if err != nil {
+		return malformed("server info", "invalid form encoding")
+	}
+
+	info := ServerInfo{
+		Name:          values.Get("name"),
+		Map:           values.Get("map"),
+		Tags:          values.Get("tags"),
+		Description:   values.Get("desc"),
+		Version:       values.Get("version"),
+		ClientVersion: values.Get("clientversion"),
+	}
+
+	if list := values.Get("playerslist"); list != "" {
+		info.PlayerList = strings.Split(list, ";")
+	}
+
+	numbers := map[string]*int{
+		"players":    &info.Players,
+		"maxplayers": &info.MaxPlayers,
+		"port":       &info.Port,
+		"modstotal":  &info.ModsTotal,
+	}
+
+	for key, field := range numbers {
+		if *field, err = strconv.Atoi(values.Get(key)); err != nil {
+			return malformed("server info", "invalid "+key)
+		}
+	}
+
+	if info.ModsTotalSize, err = strconv.ParseInt(values.Get("modstotalsize"), 10, 64); err != nil {
+		return malformed("server info", "invalid modstotalsize")
+	}
+
+	if info.Private, err = strconv.ParseBool(values.Get("private")); err != nil {
+		return malformed("server info", "invalid private")
+	}
+
+	if info.Guests, err = strconv.ParseBool(values.Get("guests")); err != nil {
+		return malformed("server info", "invalid guests")
+	}
+
+	*m = info
+	return nil
+}
+
+// EncodeJSON encodes the server info as a JSON object, for monitoring tools
+func (m *ServerInfo) EncodeJSON() []byte {
+	info := *m
+
+	// An empty player list is sent as an empty array rather than null
+	if info.PlayerList == nil {
+		info.PlayerList = []string{}
+	}
+
+	data, _ := json.Marshal(info)
+	return data
+}
@@ -0,0 +1,65 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// Message is implemented by every typed message of the VC2 protocol
+type Message interface {
+	// Encode the message into the bytes sent over the wire (excluding the TCP header)
+	Encode() []byte
+
+	// Decode the message from the bytes received over the wire (excluding the TCP header)
+	Decode(data []byte) error
+}
+
+// Returned when a message does not follow the expected format
+var ErrMalformed = errors.New("malformed message")
+
+// Create an error describing why a message could not be decoded
+func malformed(message string, reason string) error {
+	return fmt.Errorf("%w: %s - %s", ErrMalformed, message, reason)
+}
+
+// Packet wraps an encoded message in a TCP packet
+func Packet(m Message) types.TcpPacket {
+	return types.NewTcpPacket(m.Encode())
+}
+
+// Decode a message from a TCP packet
+func Decode(packet types.TcpPacket, m Message) error {
+	return m.Decode(packet.Data)
+}
+
+// FormatVehicleId formats the player and vehicle ID pair used to address a vehicle (eg. 1-0)
+func FormatVehicleId(playerId int, vehicleId int) string {
+	return strconv.Itoa(playerId) + "-" + strconv.Itoa(vehicleId)
+}
+
+// ParseVehicleId parses the player and vehicle ID pair used to address a vehicle (eg. 1-0)
+func ParseVehicleId(s string) (int, int, error) {
+	parts := strings.SplitN(s, "-", 2)
+
+	if len(parts) != 2 {
+		return 0, 0, malformed("vehicle id", "expected <player>-<vehicle>")
+	}
+
+	playerId, err := strconv.Atoi(parts[0])
+
+	if err != nil {
+		return 0, 0, malformed("vehicle id", "invalid player id")
+	}
+
+	vehicleId, err := strconv.Atoi(parts[1])
+
+	if err != nil {
+		return 0, 0, malformed("vehicle id", "invalid vehicle id")
+	}
+
+	return playerId, vehicleId, nil
+}
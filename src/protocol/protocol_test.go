@@ -0,0 +1,151 @@
+package protocol
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		message Message
+		want    string
+	}{
+		{"version", &Version{Version: "2.0"}, "VC2.0"},
+		{"auth key", &AuthKey{Key: "abc-123"}, "abc-123"},
+		{"empty mod list", &ModList{}, "-"},
+		{"mod list", &ModList{Files: []ModFile{{"/a.zip", 10}, {"/b.zip", 2048}}}, "/a.zip;/b.zip;10;2048;"},
+		{"mod request", &ModRequest{Name: "/a.zip"}, "f/a.zip"},
+		{"map", &Map{Path: "/levels/gridmap_v2/info.json"}, "M/levels/gridmap_v2/info.json"},
+		{"player id", &PlayerId{Id: 3}, "P3"},
+		{"kick", &Kick{Reason: "Server is full"}, "KServer is full"},
+		{"join", NewJoin("guest1004808"), "JWelcome guest1004808!"},
+		{"leave", NewLeave("guest1004808"), "Lguest1004808 left the server!"},
+		{"player list", &PlayerList{Count: 2, Max: 8, Names: []string{"guest1004808", "Altrius"}}, "Ss2/8:guest1004808,Altrius"},
+		{"server info", &ServerInfo{Name: "My Server", Map: "/levels/gridmap_v2/info.json", Players: 1, MaxPlayers: 8, PlayerList: []string{"Altrius"}, Port: 30814, Guests: true, ModsTotal: 1, ModsTotalSize: 2048, Version: "1.0.0", ClientVersion: "2.0.0"}, "Pclientversion=2.0.0&desc=&guests=true&map=%2Flevels%2Fgridmap_v2%2Finfo.json&maxplayers=8&modstotal=1&modstotalsize=2048&name=My+Server&players=1&playerslist=Altrius&port=30814&private=false&tags=&version=1.0.0"},
+		{"chat", &Chat{Name: "Altrius", Message: "hello: world"}, "C:Altrius: hello: world"},
+		{"event", &Event{Name: "race", Data: `{"lap":1}`}, `E:race:{"lap":1}`},
+		{"vehicle spawn", &VehicleSpawn{Roles: "USER", Name: "Altrius", PlayerId: 1, VehicleId: 0, Config: `{"jbm":"pickup"}`}, `Os:USER:Altrius:1-0:{"jbm":"pickup"}`},
+		{"vehicle edit", &VehicleEdit{PlayerId: 1, VehicleId: 2, Config: `{"jbm":"vivace"}`}, `Oc:1-2:{"jbm":"vivace"}`},
+		{"vehicle delete", &VehicleDelete{PlayerId: 1, VehicleId: 2}, "Od:1-2"},
+		{"vehicle reset", &VehicleReset{PlayerId: 1, VehicleId: 2, Data: `{"pos":{}}`}, `Or:1-2:{"pos":{}}`},
+		{"vehicle position", &VehiclePosition{PlayerId: 1, VehicleId: 2, Data: `{"pos":[1,2,3]}`}, `Zp:1-2:{"pos":[1,2,3]}`},
+	}
+
+	for _, test := range tests {
+		got := string(test.message.Encode())
+
+		if got != test.want {
+			t.Errorf("%s: expected %q, got %q", test.name, test.want, got)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		empty Message
+		want  Message
+	}{
+		{"version", "VC2.0", &Version{}, &Version{Version: "2.0"}},
+		{"auth key", "abc-123", &AuthKey{}, &AuthKey{Key: "abc-123"}},
+		{"empty mod list", "-", &ModList{}, &ModList{}},
+		{"mod list", "/a.zip;/b.zip;10;2048;", &ModList{}, &ModList{Files: []ModFile{{"/a.zip", 10}, {"/b.zip", 2048}}}},
+		{"mod request", "f/a.zip", &ModRequest{}, &ModRequest{Name: "/a.zip"}},
+		{"map", "M/levels/gridmap_v2/info.json", &Map{}, &Map{Path: "/levels/gridmap_v2/info.json"}},
+		{"player id", "P12", &PlayerId{}, &PlayerId{Id: 12}},
+		{"kick", "KBanned", &Kick{}, &Kick{Reason: "Banned"}},
+		{"join", "JWelcome Altrius!", &Join{}, &Join{Message: "Welcome Altrius!"}},
+		{"leave", "LAltrius left the server!", &Leave{}, &Leave{Message: "Altrius left the server!"}},
+		{"player list", "Ss1/8:guest1004808", &PlayerList{}, &PlayerList{Count: 1, Max: 8, Names: []string{"guest1004808"}}},
+		{"empty player list", "Ss0/8:", &PlayerList{}, &PlayerList{Count: 0, Max: 8}},
+		{"server info", "Pname=My+Server&players=2&maxplayers=8&playerslist=a%3Bb&port=30814&private=true&guests=false&modstotal=0&modstotalsize=0&version=1.0.0", &ServerInfo{}, &ServerInfo{Name: "My Server", Players: 2, MaxPlayers: 8, PlayerList: []string{"a", "b"}, Port: 30814, Private: true, Version: "1.0.0"}},
+		{"chat", "C:Altrius: hello: world", &Chat{}, &Chat{Name: "Altrius", Message: "hello: world"}},
+		{"event", `E:race:{"lap":1}`, &Event{}, &Event{Name: "race", Data: `{"lap":1}`}},
+		{"event without data", "E:ping:", &Event{}, &Event{Name: "ping", Data: ""}},
+		{"client vehicle spawn", `Os:0:{"jbm":"pickup"}`, &VehicleSpawn{}, &VehicleSpawn{Config: `{"jbm":"pickup"}`}},
+		{"server vehicle spawn", `Os:USER:Altrius:1-0:{"jbm":"pickup"}`, &VehicleSpawn{}, &VehicleSpawn{Roles: "USER", Name: "Altrius", PlayerId: 1, Config: `{"jbm":"pickup"}`}},
+		{"vehicle edit", `Oc:1-2:{"jbm":"vivace"}`, &VehicleEdit{}, &VehicleEdit{PlayerId: 1, VehicleId: 2, Config: `{"jbm":"vivace"}`}},
+		{"vehicle delete", "Od:1-2", &VehicleDelete{}, &VehicleDelete{PlayerId: 1, VehicleId: 2}},
+		{"vehicle reset", `Or:1-2:{"pos":{}}`, &VehicleReset{}, &VehicleReset{PlayerId: 1, VehicleId: 2, Data: `{"pos":{}}`}},
+		{"vehicle position", `Zp:1-2:{"pos":[1,2,3],"rot":[0,0,0,1],"vel":[4,5,6],"tim":12.5,"ping":0.05}`, &VehiclePosition{}, &VehiclePosition{PlayerId: 1, VehicleId: 2, Data: `{"pos":[1,2,3],"rot":[0,0,0,1],"vel":[4,5,6],"tim":12.5,"ping":0.05}`, Position: [3]float64{1, 2, 3}, Rotation: [4]float64{0, 0, 0, 1}, Velocity: [3]float64{4, 5, 6}, Time: 12.5, Ping: 0.05}},
+	}
+
+	for _, test := range tests {
+		err := test.empty.Decode([]byte(test.data))
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(test.empty, test.want) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.want, test.empty)
+		}
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		message Message
+	}{
+		{"version without prefix", "2.0", &Version{}},
+		{"empty auth key", "", &AuthKey{}},
+		{"long auth key", strings.Repeat("a", 51), &AuthKey{}},
+		{"mod list without sizes", "/a.zip;", &ModList{}},
+		{"mod list with invalid size", "/a.zip;big;", &ModList{}},
+		{"player id without number", "Pabc", &PlayerId{}},
+		{"player list without limit", "Ss1:guest", &PlayerList{}},
+		{"server info with invalid count", "Pplayers=many", &ServerInfo{}},
+		{"chat without separator", "C:Altrius", &Chat{}},
+		{"event without name", "E::data", &Event{}},
+		{"vehicle spawn without config", "Os:0:", &VehicleSpawn{}},
+		{"vehicle edit without ids", "Oc:{}", &VehicleEdit{}},
+		{"vehicle delete with wrong prefix", "Oc:1-2", &VehicleDelete{}},
+		{"vehicle reset with invalid id", "Or:a-b:{}", &VehicleReset{}},
+		{"vehicle position with invalid transform", "Zp:1-2:{pos", &VehiclePosition{}},
+	}
+
+	for _, test := range tests {
+		err := test.message.Decode([]byte(test.data))
+
+		if !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: expected ErrMalformed, got %v", test.name, err)
+		}
+	}
+}
+
+func TestParseVehicleId(t *testing.T) {
+	playerId, vehicleId, err := ParseVehicleId("4-7")
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if playerId != 4 || vehicleId != 7 {
+		t.Errorf("Expected 4-7, got %d-%d", playerId, vehicleId)
+	}
+
+	if FormatVehicleId(playerId, vehicleId) != "4-7" {
+		t.Errorf("Expected 4-7, got %s", FormatVehicleId(playerId, vehicleId))
+	}
+}
+
+func TestServerInfoJSON(t *testing.T) {
+	info := ServerInfo{Name: "My Server", Players: 0, MaxPlayers: 8}
+	got := string(info.EncodeJSON())
+	want := `{"name":"My Server","map":"","players":0,"maxplayers":8,"playerslist":[],"tags":"","desc":"","port":0,"private":false,"guests":false,"modstotal":0,"modstotalsize":0,"version":"","clientversion":""}`
+
+	if got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	if info.PlayerList != nil {
+		t.Errorf("Expected the server info to be left unchanged, got player list %v", info.PlayerList)
+	}
+}
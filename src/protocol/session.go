@@ -0,0 +1,295 @@
+package protocol
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// Version is sent by the client to announce its protocol version
+//
+//	VC<SEMVER>
+type Version struct {
+	Version string // The version of the client (eg. 2.0)
+}
+
+func (m *Version) Encode() []byte {
+	return []byte("VC" + m.Version)
+}
+
+func (m *Version) Decode(data []byte) error {
+	if len(data) < 3 || string(data[:2]) != "VC" {
+		return malformed("version", "expected VC<version>")
+	}
+
+	m.Version = string(data[2:])
+	return nil
+}
+
+// AuthKey is sent by the client and used to look up the player on the BeamMP API
+type AuthKey struct {
+	Key string // The public key of the player
+}
+
+func (m *AuthKey) Encode() []byte {
+	return []byte(m.Key)
+}
+
+func (m *AuthKey) Decode(data []byte) error {
+	if len(data) == 0 {
+		return malformed("auth key", "empty key")
+	}
+
+	if len(data) > types.MaxAuthKeyLength {
+		return malformed("auth key", "key is too long")
+	}
+
+	m.Key = string(data)
+	return nil
+}
+
+// A single file in a ModList
+type ModFile struct {
+	Name string // The name of the file, as requested by the client (eg. /mod.zip)
+	Size int64  // The size of the file in bytes
+}
+
+// ModList is sent by the server in response to the client's SR request
+//
+//	<name>;<name>;<size>;<size>;
+//
+// An empty mod list is sent as a single -
+type ModList struct {
+	Files []ModFile
+}
+
+func (m *ModList) Encode() []byte {
+	if len(m.Files) == 0 {
+		return []byte("-")
+	}
+
+	var builder strings.Builder
+
+	for _, file := range m.Files {
+		builder.WriteString(file.Name)
+		builder.WriteString(";")
+	}
+
+	for _, file := range m.Files {
+		builder.WriteString(strconv.FormatInt(file.Size, 10))
+		builder.WriteString(";")
+	}
+
+	return []byte(builder.String())
+}
+
+func (m *ModList) Decode(data []byte) error {
+	m.Files = nil
+
+	if string(data) == "-" {
+		return nil
+	}
+
+	fields := strings.Split(strings.TrimSuffix(string(data), ";"), ";")
+
+	if len(fields)%2 != 0 {
+		return malformed("mod list", "mismatched names and sizes")
+	}
+
+	count := len(fields) / 2
+
+	for i := 0; i < count; i++ {
+		size, err := strconv.ParseInt(fields[count+i], 10, 64)
+
+		if err != nil {
+			return malformed("mod list", "invalid file size")
+		}
+
+		m.Files = append(m.Files, ModFile{Name: fields[i], Size: size})
+	}
+
+	return nil
+}
+
+// ModRequest is sent by the client to request a file from the mod list
+//
+//	f<name>
+type ModRequest struct {
+	Name string // The name of the requested file, as sent in the mod list
+}
+
+func (m *ModRequest) Encode() []byte {
+	return []byte("f" + m.Name)
+}
+
+func (m *ModRequest) Decode(data []byte) error {
+	if len(data) < 2 || data[0] != 'f' {
+		return malformed("mod request", "expected f<name>")
+	}
+
+	m.Name = string(data[1:])
+	return nil
+}
+
+// Map is sent by the server to tell the client which map to load
+//
+//	M<path>
+type Map struct {
+	Path string // The path to the map info file (eg. /levels/gridmap_v2/info.json)
+}
+
+func (m *Map) Encode() []byte {
+	return []byte("M" + m.Path)
+}
+
+func (m *Map) Decode(data []byte) error {
+	if len(data) < 2 || data[0] != 'M' {
+		return malformed("map", "expected M<path>")
+	}
+
+	m.Path = string(data[1:])
+	return nil
+}
+
+// PlayerId is sent by the server to tell the client which ID it has been assigned
+//
+//	P<id>
+type PlayerId struct {
+	Id int
+}
+
+func (m *PlayerId) Encode() []byte {
+	return []byte("P" + strconv.Itoa(m.Id))
+}
+
+func (m *PlayerId) Decode(data []byte) error {
+	if len(data) < 2 || data[0] != 'P' {
+		return malformed("player id", "expected P<id>")
+	}
+
+	id, err := strconv.Atoi(string(data[1:]))
+
+	if err != nil {
+		return malformed("player id", "invalid id")
+	}
+
+	m.Id = id
+	return nil
+}
+
+// Kick is sent by the server before it disconnects the client
+//
+//	K<reason>
+type Kick struct {
+	Reason string
+}
+
+func (m *Kick) Encode() []byte {
+	return []byte("K" + m.Reason)
+}
+
+func (m *Kick) Decode(data []byte) error {
+	if len(data) < 1 || data[0] != 'K' {
+		return malformed("kick", "expected K<reason>")
+	}
+
+	m.Reason = string(data[1:])
+	return nil
+}
+
+// Join is broadcast by the server when a player has joined
+//
+//	J<message>
+type Join struct {
+	Message string
+}
+
+// Create the standard join message for a player
+func NewJoin(name string) *Join {
+	return &Join{Message: "Welcome " + name + "!"}
+}
+
+func (m *Join) Encode() []byte {
+	return []byte("J" + m.Message)
+}
+
+func (m *Join) Decode(data []byte) error {
+	if len(data) < 1 || data[0] != 'J' {
+		return malformed("join", "expected J<message>")
+	}
+
+	m.Message = string(data[1:])
+	return nil
+}
+
+// Leave is broadcast by the server when a player has left
+//
+//	L<message>
+type Leave struct {
+	Message string
+}
+
+// Create the standard leave message for a player
+func NewLeave(name string) *Leave {
+	return &Leave{Message: name + " left the server!"}
+}
+
+func (m *Leave) Encode() []byte {
+	return []byte("L" + m.Message)
+}
+
+func (m *Leave) Decode(data []byte) error {
+	if len(data) < 1 || data[0] != 'L' {
+		return malformed("leave", "expected L<message>")
+	}
+
+	m.Message = string(data[1:])
+	return nil
+}
+
+// PlayerList is sent by the server to populate the player list of the client
+//
+//	Ss<count>/<max>:<name>,<name>
+type PlayerList struct {
+	Count int      // The number of players on the server
+	Max   int      // The maximum number of players on the server
+	Names []string // The names shown in the player list
+}
+
+func (m *PlayerList) Encode() []byte {
+	return []byte("Ss" + strconv.Itoa(m.Count) + "/" + strconv.Itoa(m.Max) + ":" + strings.Join(m.Names, ","))
+}
+
+func (m *PlayerList) Decode(data []byte) error {
+	if len(data) < 2 || string(data[:2]) != "Ss" {
+		return malformed("player list", "expected Ss<count>/<max>:<names>")
+	}
+
+	parts := strings.SplitN(string(data[2:]), ":", 2)
+	counts := strings.SplitN(parts[0], "/", 2)
+
+	if len(parts) != 2 || len(counts) != 2 {
+		return malformed("player list", "expected Ss<count>/<max>:<names>")
+	}
+
+	count, err := strconv.Atoi(counts[0])
+
+	if err != nil {
+		return malformed("player list", "invalid player count")
+	}
+
+	limit, err := strconv.Atoi(counts[1])
+
+	if err != nil {
+		return malformed("player list", "invalid player limit")
+	}
+
+	m.Count, m.Max, m.Names = count, limit, nil
+
+	if parts[1] != "" {
+		m.Names = strings.Split(parts[1], ",")
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package protocol
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// The second byte of a vehicle (O) message
+const (
+	VehicleSpawnCode  = 's'
+	VehicleEditCode   = 'c'
+	VehicleDeleteCode = 'd'
+	VehicleResetCode  = 'r'
+)
+
+// VehicleSpawn is sent when a vehicle is spawned.
+//
+// The client sends the short form, leaving the ownership fields empty:
+//
+//	Os:0:<config>
+//
+// The server relays the full form to the other players:
+//
+//	Os:<roles>:<name>:<player>-<vehicle>:<config>
+type VehicleSpawn struct {
+	Roles     string // The roles of the owner
+	Name      string // The name of the owner
+	PlayerId  int    // The ID of the owner
+	VehicleId int    // The ID of the vehicle, assigned by the server
+	Config    string // The vehicle configuration (JSON)
+}
+
+func (m *VehicleSpawn) Encode() []byte {
+	return []byte("Os:" + m.Roles + ":" + m.Name + ":" + FormatVehicleId(m.PlayerId, m.VehicleId) + ":" + m.Config)
+}
+
+func (m *VehicleSpawn) Decode(data []byte) error {
+	if len(data) < 4 || string(data[:3]) != "Os:" {
+		return malformed("vehicle spawn", "expected Os:")
+	}
+
+	text := string(data[3:])
+	start := strings.IndexByte(text, '{')
+
+	if start == -1 {
+		return malformed("vehicle spawn", "missing vehicle configuration")
+	}
+
+	m.Config = text[start:]
+	fields := strings.Split(strings.TrimSuffix(text[:start], ":"), ":")
+
+	switch len(fields) {
+	case 1:
+		// The short form sent by the client does not carry any ownership information
+		m.Roles, m.Name, m.PlayerId, m.VehicleId = "", "", 0, 0
+		return nil
+	case 3:
+		playerId, vehicleId, err := ParseVehicleId(fields[2])
+
+		if err != nil {
+			return err
+		}
+
+		m.Roles, m.Name, m.PlayerId, m.VehicleId = fields[0], fields[1], playerId, vehicleId
+		return nil
+	default:
+		return malformed("vehicle spawn", "unexpected number of fields")
+	}
+}
+
+// VehicleEdit is sent when the configuration of a vehicle changes
+//
+//	Oc:<player>-<vehicle>:<config>
+type VehicleEdit struct {
+	PlayerId  int    // The ID of the owner
+	VehicleId int    // The ID of the vehicle
+	Config    string // The new vehicle configuration (JSON)
+}
+
+func (m *VehicleEdit) Encode() []byte {
+	return []byte("Oc:" + FormatVehicleId(m.PlayerId, m.VehicleId) + ":" + m.Config)
+}
+
+func (m *VehicleEdit) Decode(data []byte) error {
+	playerId, vehicleId, rest, err := decodeVehicleMessage(data, "Oc:", "vehicle edit")
+
+	if err != nil {
+		return err
+	}
+
+	m.PlayerId, m.VehicleId, m.Config = playerId, vehicleId, rest
+	return nil
+}
+
+// VehicleDelete is sent when a vehicle is removed
+//
+//	Od:<player>-<vehicle>
+type VehicleDelete struct {
+	PlayerId  int // The ID of the owner
+	VehicleId int // The ID of the vehicle
+}
+
+func (m *VehicleDelete) Encode() []byte {
+	return []byte("Od:" + FormatVehicleId(m.PlayerId, m.VehicleId))
+}
+
+func (m *VehicleDelete) Decode(data []byte) error {
+	playerId, vehicleId, _, err := decodeVehicleMessage(data, "Od:", "vehicle delete")
+
+	if err != nil {
+		return err
+	}
+
+	m.PlayerId, m.VehicleId = playerId, vehicleId
+	return nil
+}
+
+// VehicleReset is sent when a vehicle is reset in place
+//
+//	Or:<player>-<vehicle>:<data>
+type VehicleReset struct {
+	PlayerId  int    // The ID of the owner
+	VehicleId int    // The ID of the vehicle
+	Data      string // The position the vehicle was reset to (JSON)
+}
+
+func (m *VehicleReset) Encode() []byte {
+	return []byte("Or:" + FormatVehicleId(m.PlayerId, m.VehicleId) + ":" + m.Data)
+}
+
+func (m *VehicleReset) Decode(data []byte) error {
+	playerId, vehicleId, rest, err := decodeVehicleMessage(data, "Or:", "vehicle reset")
+
+	if err != nil {
+		return err
+	}
+
+	m.PlayerId, m.VehicleId, m.Data = playerId, vehicleId, rest
+	return nil
+}
+
+// Decode the common <prefix><player>-<vehicle>[:<rest>] layout shared by vehicle messages
+func decodeVehicleMessage(data []byte, prefix string, name string) (int, int, string, error) {
+	if len(data) <= len(prefix) || string(data[:len(prefix)]) != prefix {
+		return 0, 0, "", malformed(name, "expected "+prefix+"<player>-<vehicle>")
+	}
+
+	parts := strings.SplitN(string(data[len(prefix):]), ":", 2)
+
+	playerId, vehicleId, err := ParseVehicleId(parts[0])
+
+	if err != nil {
+		return 0, 0, "", err
+	}
+
+	if len(parts) == 1 {
+		return playerId, vehicleId, "", nil
+	}
+
+	return playerId, vehicleId, parts[1], nil
+}
+
+// VehicleCode returns the sub code of a vehicle message (eg. s for Os:), or 0 if there is none
+func VehicleCode(data []byte) byte {
+	if len(data) < 2 || data[0] != 'O' {
+		return 0
+	}
+
+	return data[1]
+}
+
+// VehiclePosition is sent over UDP with the latest transform of a vehicle
+//
+//	Zp:<player>-<vehicle>:<data>
+//
+// The data is relayed to the other players unchanged, so it is kept alongside the decoded fields.
+type VehiclePosition struct {
+	PlayerId  int    // The ID of the owner
+	VehicleId int    // The ID of the vehicle
+	Data      string // The transform of the vehicle (JSON)
+
+	Position [3]float64 `json:"pos"`  // The position of the vehicle
+	Rotation [4]float64 `json:"rot"`  // The rotation of the vehicle, as a quaternion
+	Velocity [3]float64 `json:"vel"`  // The velocity of the vehicle
+	Time     float64    `json:"tim"`  // The game time the transform was taken at
+	Ping     float64    `json:"ping"` // The latency of the sender, as measured by the client (in seconds)
+}
+
+func (m *VehiclePosition) Encode() []byte {
+	return []byte("Zp:" + FormatVehicleId(m.PlayerId, m.VehicleId) + ":" + m.Data)
+}
+
+func (m *VehiclePosition) Decode(data []byte) error {
+	playerId, vehicleId, rest, err := decodeVehicleMessage(data, "Zp:", "vehicle position")
+
+	if err != nil {
+		return err
+	}
+
+	position := VehiclePosition{PlayerId: playerId, VehicleId: vehicleId, Data: rest}
+
+	if err = json.Unmarshal([]byte(rest), &position); err != nil {
+		return malformed("vehicle position", "invalid transform")
+	}
+
+	*m = position
+	return nil
+}
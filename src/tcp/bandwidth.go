@@ -0,0 +1,99 @@
+package tcp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/bandwidth"
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// How often the mod download throughput is logged, while downloads are running
+const DownloadStatsInterval = 30 * time.Second
+
+// The mod download throughput of a single client
+type ClientThroughput struct {
+	Address string // The address of the connection
+	Player  string // The name of the player, if they have authenticated
+	Rate    int64  // The current download rate, in bytes per second
+	Total   int64  // The number of bytes sent to the client
+}
+
+// The mod download throughput of the server
+type DownloadStats struct {
+	Rate       int64              // The current download rate across all clients, in bytes per second
+	Total      int64              // The number of bytes sent across all clients
+	Limit      int64              // The download rate limit across all clients, in bytes per second (0 when unlimited)
+	PerClient  int64              // The download rate limit for each client, in bytes per second (0 when unlimited)
+	Throughput []ClientThroughput // The clients which are currently downloading mods
+}
+
+// Take bandwidth from the server and client limits, waiting until n bytes of mod data may be sent
+func (c *TCPConnection) waitForBandwidth(n int) bool {
+	return bandwidth.Wait(n, c.done, c.Parent.DownloadRate, c.downloadRate)
+}
+
+// DownloadStats returns a snapshot of the mod download throughput
+func (s *Server) DownloadStats() DownloadStats {
+	stats := DownloadStats{
+		Rate:       s.DownloadSent.Rate(),
+		Total:      s.DownloadSent.Total(),
+		Limit:      s.DownloadRate.Rate(),
+		PerClient:  int64(config.Configuration.NetBeams.DownloadLimitPerClient) * 1024,
+		Throughput: []ClientThroughput{},
+	}
+
+	for _, c := range s.Connections.Snapshot() {
+		rate := c.downloadSent.Rate()
+
+		if rate == 0 {
+			continue
+		}
+
+		stats.Throughput = append(stats.Throughput, ClientThroughput{
+			Address: c.Address,
+			Player:  c.GetPlayer().DisplayName,
+			Rate:    rate,
+			Total:   c.downloadSent.Total(),
+		})
+	}
+
+	return stats
+}
+
+// DownloadStatsLoop logs the mod download throughput while downloads are running, until the server stops
+func (s *Server) DownloadStatsLoop() {
+	ticker := time.NewTicker(DownloadStatsInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if *s.Status != types.StatusHealthy {
+			return
+		}
+
+		stats := s.DownloadStats()
+
+		if len(stats.Throughput) == 0 {
+			continue
+		}
+
+		s.Infof("Sending mods to %d clients at %s", len(stats.Throughput), FormatRate(stats.Rate))
+
+		for _, client := range stats.Throughput {
+			s.Debugf("Sending mods to %s (%s) at %s - %d bytes sent", client.Player, client.Address, FormatRate(client.Rate), client.Total)
+		}
+	}
+}
+
+// FormatRate formats a rate in bytes per second for logging
+func FormatRate(rate int64) string {
+	switch {
+	case rate >= 1024*1024:
+		return fmt.Sprintf("%.1f MB/s", float64(rate)/(1024*1024))
+	case rate >= 1024:
+		return fmt.Sprintf("%.1f KB/s", float64(rate)/1024)
+	default:
+		return fmt.Sprintf("%d B/s", rate)
+	}
+}
@@ -0,0 +1,76 @@
+package tcp
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/bans"
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// Get the ban service, if it is running
+func banService() (*bans.BanService, bool) {
+	bs, ok := types.App.GetService("Bans").(*bans.BanService)
+	return bs, ok
+}
+
+// Get the IP address of the remote end of the connection
+func (c *TCPConnection) RemoteIP() netip.Addr {
+	if addr, ok := c.Conn.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.AddrPort().Addr().Unmap()
+	}
+
+	return netip.Addr{}
+}
+
+// CheckBan returns the ban which applies to an account connecting through this connection, if there is one
+func (c *TCPConnection) CheckBan(account *types.Account) (*bans.Ban, error) {
+	bs, ok := banService()
+
+	if !ok {
+		return nil, nil
+	}
+
+	subject := bans.Subject{Address: c.RemoteIP()}
+
+	if account != nil {
+		subject.AccountId = account.Id
+		subject.Identifiers = account.Identifiers
+	}
+
+	return bs.Check(subject)
+}
+
+// KickWithBan disconnects the player, and bans them for the given duration so that they cannot rejoin straight away.
+// Durations which are not positive only kick the player, as a negative duration disables the ban.
+func (c *TCPConnection) KickWithBan(reason string, duration time.Duration, issuer string) {
+	if bs, ok := banService(); ok && duration > 0 {
+		if err := bs.TempBan(c.GetPlayer().Account, c.RemoteIP(), duration, reason, issuer); err != nil {
+			c.Error("Error banning player - Additional output below")
+			c.Error(err.Error())
+		}
+	}
+
+	c.Disconnect(reason)
+}
+
+// KickPlayer kicks a player on behalf of an admin, banning them for the configured admin kick duration
+func (s *Server) KickPlayer(id int, reason string, issuer string) error {
+	c := s.Connections.GetByPlayerId(id)
+
+	if c == nil {
+		return fmt.Errorf("player %d is not connected", id)
+	}
+
+	if reason == "" {
+		reason = "Kicked by an admin"
+	}
+
+	s.Infof("Kicking player %d on behalf of %s - Reason: %s", id, issuer, reason)
+	c.KickWithBan(reason, config.Configuration.Auth.Kick.AdminDurationTime, issuer)
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package tcp
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/bans"
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// Start a ban service backed by a temporary ban file
+func newTestBans(t *testing.T) *bans.BanService {
+	t.Helper()
+
+	config.Configuration.NetBeams.BanStore = "file"
+	config.Configuration.NetBeams.BanFile = filepath.Join(t.TempDir(), "Bans.json")
+
+	bs := bans.Service()
+
+	if err := bs.StartService(); err != nil {
+		t.Fatal(err)
+	}
+
+	types.App.AddService(bs)
+
+	t.Cleanup(func() {
+		_ = types.App.RemoveService(bs.GetName())
+	})
+
+	return bs
+}
+
+func TestQuotaKickBansPlayer(t *testing.T) {
+	s := newTestServer(t)
+	newTestBans(t)
+
+	tests := []struct {
+		duration time.Duration
+		banned   bool
+	}{
+		{time.Hour, true},
+		{-1, false}, // A negative duration only kicks the player
+	}
+
+	for id, test := range tests {
+		config.Configuration.Auth.Kick.OnlineDurationTime = test.duration
+
+		account := &types.Account{Name: "driver", Id: "account-" + test.duration.String()}
+		c, client := newTestConnection(t, s)
+		c.SetPlayer(types.Player{PlayerId: id, Account: account})
+
+		kicked := make(chan struct{})
+		go func() {
+			s.HandleQuota(id, 0)
+			close(kicked)
+		}()
+
+		reader := types.NewFrameReader(client, types.MaxHeaderSize)
+
+		if packet := readFrame(t, reader, client); string(packet.Data) != "K"+QuotaKickReason {
+			t.Errorf("Expected the player to be kicked, got %q", packet.Data)
+		}
+
+		waitClosed(t, kicked, "the kick")
+
+		// The ban replaces the rejoin cooldown, so it is checked when the player next authenticates
+		ban, err := c.CheckBan(account)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !test.banned {
+			if ban != nil {
+				t.Errorf("%s: expected no ban, got %+v", test.duration, ban)
+			}
+			continue
+		}
+
+		if ban == nil || ban.Issuer != "Online quota" || ban.Reason != QuotaKickReason {
+			t.Fatalf("%s: expected a ban for running out of online time, got %+v", test.duration, ban)
+		}
+
+		if remaining := ban.Remaining(time.Now()); remaining <= 59*time.Minute || remaining > time.Hour {
+			t.Errorf("%s: expected the ban to last an hour, %s remains", test.duration, remaining)
+		}
+	}
+}
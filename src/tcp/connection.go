@@ -1,392 +1,313 @@
 package tcp
 
 import (
+	"errors"
 	"net"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/Masterminds/semver/v3"
+	"github.com/altriusrs/netbeams/src/bandwidth"
 	"github.com/altriusrs/netbeams/src/config"
-	"github.com/altriusrs/netbeams/src/environment"
-	"github.com/altriusrs/netbeams/src/http"
 	"github.com/altriusrs/netbeams/src/logs"
 	"github.com/altriusrs/netbeams/src/netcheck"
 	"github.com/altriusrs/netbeams/src/player_manager"
+	"github.com/altriusrs/netbeams/src/protocol"
 	"github.com/altriusrs/netbeams/src/types"
 )
 
+// The number of chunks of bulk data which may be waiting to be sent to a client
+const BulkQueueSize = 16
+
 type TCPConnection struct {
 	logs.Logger
-	Address     string                        // Connection address
-	Conn        net.Conn                      // Connection
-	Parent      *Server                       // Parent server
-	State       types.State                   // Connection state
-	Player      types.Player                  // Player
-	reservation *int                          // Player reservation ID
-	nc          *netcheck.NetCheckService     // NetCheck service
-	pm          *player_manager.PlayerManager // Player Manager service
+	Address      string                        // Connection address
+	Conn         net.Conn                      // Connection
+	Parent       *Server                       // Parent server
+	State        types.State                   // Connection state
+	Player       types.Player                  // Player
+	reader       *types.FrameReader            // Buffered frame reader for the connection
+	outbound     chan []byte                   // Queue of serialized frames waiting to be written
+	bulk         chan []byte                   // Queue of bulk data (such as mod files) waiting to be written
+	done         chan struct{}                 // Closed when the connection is shutting down
+	flushed      chan struct{}                 // Closed when the writer goroutine has exited
+	closer       *sync.Once                    // Ensures the done channel is only closed once
+	writer       *sync.Once                    // Ensures the writer goroutine is only started once
+	mutex        sync.RWMutex                  // Guards the state and player, which are read by other goroutines
+	reservation  *int                          // Player reservation ID
+	authorized   bool                          // Whether the connection completed authentication
+	download     bool                          // Whether this is a download (D) connection, which only carries raw file data
+	downloads    chan *TCPConnection           // Receives the download connection opened by the client
+	downloadConn *TCPConnection                // The download connection attached to this session
+	downloadRate *bandwidth.Bucket             // Limits the rate mods are sent to this client
+	downloadSent *bandwidth.Meter              // Measures the rate mods are sent to this client
+	nc           *netcheck.NetCheckService     // NetCheck service
+	pm           *player_manager.PlayerManager // Player Manager service
 }
 
-func NewTCPConnection(conn net.Conn, addr string, parent *Server) TCPConnection {
-	return TCPConnection{
-		Address: addr,
-		Conn:    conn,
-		Parent:  parent,
-		Logger:  logs.NetLogger("TCP-" + addr),
-		State:   types.StateUnknown,
-		nc:      types.App.GetService("NetCheck").(*netcheck.NetCheckService),
-		pm:      types.App.GetService("Player Manager").(*player_manager.PlayerManager),
+func NewTCPConnection(conn net.Conn, addr string, parent *Server) *TCPConnection {
+	// NetCheck is optional, so it may not be running
+	nc, _ := types.App.GetService("NetCheck").(*netcheck.NetCheckService)
+
+	return &TCPConnection{
+		Address:      addr,
+		Conn:         conn,
+		Parent:       parent,
+		Logger:       logs.NetLogger("TCP-" + addr),
+		State:        types.StateUnknown,
+		reader:       types.NewFrameReader(conn, types.MaxHeaderSize),
+		outbound:     make(chan []byte, config.Configuration.NetBeams.SendQueueSize),
+		bulk:         make(chan []byte, BulkQueueSize),
+		done:         make(chan struct{}),
+		flushed:      make(chan struct{}),
+		closer:       &sync.Once{},
+		writer:       &sync.Once{},
+		downloads:    make(chan *TCPConnection, 1),
+		downloadRate: bandwidth.NewBucket(int64(config.Configuration.NetBeams.DownloadLimitPerClient) * 1024),
+		downloadSent: bandwidth.NewMeter(),
+		nc:           nc,
+		pm:           types.App.GetService("Player Manager").(*player_manager.PlayerManager),
 	}
 }
 
 func (c *TCPConnection) SetState(state types.State) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
 	if c.State != state {
 		c.Infof("Connection %s state changed from %s to %s", c.Address, c.State, state)
 		c.State = state
 	}
 }
 
-func (c *TCPConnection) Listen() {
-	c.Info("Listening for messages")
-
-	defer c.Info("Connection closed")
-	defer c.Close()
-
-	defer c.Terminate()
-
-	// Identify and authenticate the connection
-	c.Identify()
-
-	// Sync mod data and server info to the client
-	c.SyncModData()
-
-	for c.State == types.StatePlaying {
-		breakLoop := c.RuntimeLoop()
-		if breakLoop {
-			c.Kick("Connection closed by server")
-			return
-		}
-	}
+// Get the state of the connection (safe to call from other goroutines)
+func (c *TCPConnection) GetState() types.State {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 
+	return c.State
 }
 
-func (c *TCPConnection) Write(data types.TcpPacket) {
-	c.Debugf("Writing to connection %s - %d bytes", c.Address, data.Header)
+// Get a copy of the player attached to the connection (safe to call from other goroutines)
+func (c *TCPConnection) GetPlayer() types.Player {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 
-	_, err := c.Conn.Write(data.Serialize())
-	if err != nil {
-		c.Error("Error writing to connection - Additional output below")
-		c.Error(err.Error())
-	}
+	return c.Player
 }
 
-func (c *TCPConnection) Identify() {
-	c.SetState(types.StateIdentify)
-
-	// Read the first message
-	sState := make([]byte, 1)
-	_, err := c.Conn.Read(sState)
-	if err != nil {
-		c.Error("Error reading from connection - Additional output below")
-		c.Error(err.Error())
-		return
-	}
-
-	c.Debugf("Received state: %s", sState)
+// Attach a player to the connection
+func (c *TCPConnection) SetPlayer(player types.Player) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	switch sState[0] {
-	case 'C':
-		c.SetState(types.StateAuthenticate)
-		c.Authenticate()
-	case 'D':
-		c.SetState(types.StateDownload)
-	case 'P':
-		c.Write(types.NewTcpPacket("P"))
-		c.SetState(types.StatePingOnly)
-	default:
-		c.Error("Unknown starting state - Disconnecting - Additional output below")
-		c.Errorf("Unknown starting state: %s", sState)
-		c.Kick("Unknown starting state")
-		return
-	}
+	c.Player = player
 }
 
-func (c *TCPConnection) Authenticate() {
-	c.SetState(types.StateAuthenticate)
-
-	// Read the version information from the client
-	packet, err := types.ReadTcpPacket(c.Conn)
-
-	if err != nil {
-		c.Kick("Unable to read data")
-		c.Error("Error authenticating - Additional output below")
-		c.Fatal(err)
-		return
-	}
-
-	text := packet.ToString()
-
-	rawVersion := text[2:]
-
-	// Parse the version provided by the client
-	version, err := semver.NewVersion(rawVersion)
-
-	if err != nil {
-		c.Kick("Unable to parse version")
-		c.Error("Error authenticating - Additional output below")
-		c.Fatal(err)
-		c.Error(rawVersion)
-		return
-	}
-
-	if !environment.Context.SemverMaxClientVersion.Check(version) {
-		c.Kick("Client version is too old")
-		c.Error("Error authenticating - Additional output below")
-		c.Fatal(err)
-		return
-	}
-
-	if !environment.Context.SemverMinClientVersion.Check(version) {
-		c.Kick("Client version is too new")
-		c.Error("Error authenticating - Additional output below")
-		c.Fatal(err)
-		return
-	}
-
-	c.Debugf("Client version: %s - Continuing authentication", version)
+// Read the player attached to the connection whilst holding the connection lock.
+// Unlike GetPlayer, this is safe for reading the vehicles of the player, which are shared.
+func (c *TCPConnection) ViewPlayer(view func(player *types.Player)) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
 
-	// The client version is valid, we can now read the authentication key
-	c.Write(types.NewTcpPacket("A"))
-
-	packet, err = types.ReadTcpPacket(c.Conn)
-
-	if err != nil {
-		c.Kick("Unable to read data")
-		c.Error("Error authenticating - Additional output below")
-		c.Fatal(err)
-		return
-	}
-
-	key := packet.ToString()
+	view(&c.Player)
+}
 
-	if len(key) > types.MaxAuthKeyLength {
-		c.Kick("Authentication key is too long")
-		c.Error("Error authenticating - Additional output below")
-		c.Fatal(err)
-		return
-	}
+// Modify the player attached to the connection whilst holding the connection lock
+func (c *TCPConnection) UpdatePlayer(update func(player *types.Player)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 
-	if config.Configuration.Auth.Proxy.Enable || config.Configuration.Auth.VPN.Enable {
-		asn, err := c.nc.Check(c.Address)
+	update(&c.Player)
+}
 
-		if err != nil {
-			c.Kick("Unable to get ASN information")
-			c.Error("Error authenticating - Additional output below")
-			c.Fatal(err)
-			return
-		}
+func (c *TCPConnection) Listen() {
+	c.Info("Listening for messages")
 
-		c.Debugf("IsProxy: %s", strconv.Itoa(int(asn.IsProxy)))
-		c.Debugf("ProxyType: %s", asn.ProxyType)
-		c.Debugf("CountryShort: %s", asn.CountryShort)
-		c.Debugf("CountryLong: %s", asn.CountryLong)
-		c.Debugf("Region: %s", asn.Region)
-		c.Debugf("City: %s", asn.City)
-		c.Debugf("Isp: %s", asn.Isp)
-		c.Debugf("Domain: %s", asn.Domain)
-		c.Debugf("UsageType: %s", asn.UsageType)
-		c.Debugf("Asn: %s", asn.Asn)
-		c.Debugf("As: %s", asn.As)
-		c.Debugf("LastSeen: %s", asn.LastSeen)
-		c.Debugf("Threat: %s", asn.Threat)
-	}
+	defer c.Info("Connection closed")
+	defer c.Close()
 
-	pid, err := c.pm.ReserveSlotForConnection(nil)
+	defer c.Terminate()
 
-	if err != nil {
-		if err.Error() == "server is full" {
-			c.Kick("Server is full")
-			return
-		} else {
-			c.Kick("The server is experiencing an error - Please try again later")
-			c.Error("Error authenticating - Additional output below")
-			c.Error(err.Error())
-			return
-		}
-	}
+	// Start draining the send queue
+	c.StartWriter()
 
-	c.reservation = pid // Save the reservation ID
+	// Identify the connection, which then runs until the session is over
+	c.Identify()
+}
 
-	c.Debugf("Authentication key: %s", key)
+// Queue a packet to be sent to the connection
+func (c *TCPConnection) Write(data types.TcpPacket) {
+	c.Debugf("Writing to connection %s - %d bytes", c.Address, data.Header)
 
-	player, err := types.App.GetService("BeamMP API").(*http.API).AuthenticatePlayer(key)
+	c.enqueue(data.Serialize())
+}
 
-	if err != nil {
-		c.Kick("Unable to authenticate player")
-		c.Error("Error authenticating - Additional output below")
-		c.Fatal(err)
-		return
+// Queue raw data to be sent to the connection, waiting for space in the bulk queue.
+// This is used for bulk transfers, where the client is expected to fall behind,
+// and is only written when there are no packets waiting in the send queue.
+func (c *TCPConnection) WriteRaw(data []byte) bool {
+	select {
+	case c.bulk <- data:
+		return true
+	case <-c.done:
+		return false
 	}
+}
 
-	c.Player = player.IntoPlayerEntity()
-
-	c.Player.PlayerId = *pid
-
-	if player == nil {
-		c.Kick("Unable to authenticate player")
-		c.Error("Error authenticating - Additional output below")
-		c.Fatal(err)
-		return
+// Add a frame to the send queue, disconnecting the client if the queue is full
+func (c *TCPConnection) enqueue(frame []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
 	}
 
-	c.Debugf("Player: %s", player.Name)
-	c.Debugf("UID: %s", player.Uid)
-	c.Debugf("Roles: %s", player.Roles)
-	c.Debugf("Identifiers: %s", player.Identifiers)
-	c.Debugf("Is Guest?: %t", player.Guest)
-	c.Infof("Changing logger ID to %s", player.Name)
-	c.Module = player.Name
-
-	if config.Configuration.General.Password != "" {
-		success := c.HandlePassword()
-
-		if !success {
-			c.Kick("Unable to authenticate player")
-			c.Error("Error authenticating - Failed to send valid password")
-			return
-		}
+	select {
+	case c.outbound <- frame:
+		return true
+	default:
+		c.Errorf("Send queue is full (%d packets) - Disconnecting slow client", cap(c.outbound))
+		c.Evict("Send queue overflow")
+		return false
 	}
-
 }
 
-// HandlePassword handles the password authentication
-// TODO: Add password authentication support when that is better understood
-func (c *TCPConnection) HandlePassword() bool {
-	// Since passwords are not supported, we can merely return false,
-	// to indicate that the password was not accepted
-	return false
-
-	// c.SetState(types.StatePassword)
-
-	// c.Debug("Sending password request")
-	// c.Write(types.NewTcpPacket("S"))
-	// c.SetState(types.Password)
-
-	// // Read the password from the client
-	// packet, err := ReadPacket(c.Conn)
-
-	// if err != nil {
-	// 	c.Kick("Unable to read data")
-	// 	c.SetStatus(types.Errored)
-	// 	c.Error("Error authenticating - Additional output below")
-	// 	c.Fatal(err)
-	// 	return false
-	// }
-
-	// password := packet.ToString()
-
-	// c.Debugf("Password: %s", password)
-
-	// // if password != config.Configuration.General.Password {
-	// // 	c.Kick("Invalid password")
-	// // 	c.SetStatus(types.Errored)
-	// // 	c.Error("Error authenticating - Invalid password")
-	// // 	return false
-	// // }
-
-	// // wait 10 secodns before closing
-	// time.Sleep(10 * time.Second)
-
-	// return false
+// StartWriter starts the goroutine which drains the send queue, if it is not already running.
+// Anything waiting for the queue to be flushed must start it first, or it would wait forever.
+func (c *TCPConnection) StartWriter() {
+	c.writer.Do(func() {
+		go c.WriteLoop()
+	})
 }
 
-func (c *TCPConnection) SyncModData() {
-	c.Debug("Client is preparing to sync mod data")
-
-	playerCount := len(c.Parent.Connections) - 1
-
-	c.SetState(types.StateDownload)
-
-	c.Write(types.NewTcpPacket("P" + strconv.Itoa(playerCount)))
-
-	pauseStart := time.Now()
+// WriteLoop writes queued frames to the connection until the connection is closed.
+// This is the only goroutine which writes to the underlying connection (except for download connections).
+// Packets in the send queue always take priority over bulk data.
+func (c *TCPConnection) WriteLoop() {
+	defer close(c.flushed)
 
 	for {
-		packet, err := types.ReadTcpPacket(c.Conn)
-		if err != nil {
-			if time.Since(pauseStart) > 5*time.Second {
-				c.Kick("Unable to read data")
-				c.Error("Error reading from connection - Additional output below")
-				c.Error(err.Error())
-				return
-			} else {
-				c.Kick("Unable to read data")
-				c.Error("Error reading from connection - Additional output below")
-				c.Error(err.Error())
+		select {
+		case frame := <-c.outbound:
+			if !c.writeFrame(frame) {
 				return
 			}
-
+			continue
+		default:
 		}
 
-		c.Debugf("Received packet: %v", packet)
-
-		if packet.IsEmpty() {
-			c.Error("Failed to read packet from client - Malformed?")
-			break
-		} else if packet.Code(0) == 'f' {
-			// The client is requesting a file
-		} else if packet.Code(0) == 'S' {
-			if packet.Code(1) == 'R' {
-				// the client is requesting mod data
-				// Since we do not support mods, we send an empty mod list
-				c.Write(types.NewTcpPacket("-"))
-			} else {
-				c.Error("The client sent an unknown request.")
-				c.Kick("The client sent an unknown request.")
-				break
+		select {
+		case frame := <-c.outbound:
+			if !c.writeFrame(frame) {
+				return
+			}
+		case data := <-c.bulk:
+			if !c.writeFrame(data) {
+				return
+			}
+		case <-c.done:
+			// Flush anything still queued (such as a kick message) before exiting
+			for {
+				select {
+				case frame := <-c.outbound:
+					if !c.writeFrame(frame) {
+						return
+					}
+				default:
+					return
+				}
 			}
-		} else if packet.String() == "Done" {
-			c.Debug("Client mod list synced")
-			c.SetState(types.StateMapLoad)
-			break
 		}
-		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// Write a single frame to the connection, respecting the configured write timeout
+func (c *TCPConnection) writeFrame(frame []byte) bool {
+	if timeout := config.Configuration.NetBeams.WriteTimeoutTime; timeout > 0 {
+		_ = c.Conn.SetWriteDeadline(time.Now().Add(timeout))
 	}
 
-	if c.State != types.StateMapLoad {
-		c.Kick("Unable to sync mod data")
-		return
+	_, err := c.Conn.Write(frame)
+	if err != nil {
+		c.Error("Error writing to connection - Additional output below")
+		c.Error(err.Error())
+		c.Evict("Write failed")
+		return false
 	}
 
-	c.Debug("Sending map files")
+	return true
+}
 
-	c.Write(types.NewTcpPacket("M" + config.Configuration.General.Map))
+// Evict disconnects the client immediately, without waiting for queued packets to be sent
+func (c *TCPConnection) Evict(reason string) {
+	c.Warnf("Evicting connection %s - Reason: %s", c.Address, reason)
+	c.closer.Do(func() {
+		close(c.done)
+	})
+	_ = c.Conn.Close()
+}
 
-	packet, err := types.ReadTcpPacket(c.Conn)
+func (c *TCPConnection) Identify() {
+	c.SetState(types.StateIdentify)
 
+	// Read the first message
+	sState := make([]byte, 1)
+	var err error
+	sState[0], err = c.reader.ReadByte()
 	if err != nil {
 		c.Error("Error reading from connection - Additional output below")
 		c.Error(err.Error())
 		return
 	}
 
-	if packet.Code(0) == 'H' {
-		c.Info("Client is connected and loaded")
-		c.SetState(types.StatePlaying)
-	} else {
-		c.Warn("Client may not be loaded - Unrecognized map load response")
+	c.Debugf("Received state: %s", sState)
+
+	switch sState[0] {
+	case 'C':
+		c.SetState(types.StateAuthenticate)
+		c.Negotiate()
+	case 'D':
+		c.SetState(types.StateDownload)
+		c.ServeDownload()
+	case 'P':
+		c.SetState(types.StatePingOnly)
+		c.Probe()
+	default:
+		c.Error("Unknown starting state - Disconnecting - Additional output below")
+		c.Errorf("Unknown starting state: %s", sState)
+		c.Kick("Unknown starting state")
+		return
 	}
 }
 
 func (c *TCPConnection) Close() {
 	c.Info("Closing connection")
+	c.closeDownloadSocket()
+
 	if c.Conn != nil {
-		c.Kick("Server shutting down")
+		// Download connections only carry raw file data, so they cannot be sent a kick message,
+		// and probes are closed as soon as they have their answer
+		if !c.download && c.GetState() != types.StatePingOnly {
+			c.Kick("Server shutting down")
+		}
+
+		// Stop the writer and give it the chance to flush the send queue
+		c.StartWriter()
+		c.closer.Do(func() {
+			close(c.done)
+		})
+		<-c.flushed
+
 		_ = c.Conn.Close()
 		c.SetState(types.StateDisconnected)
 	}
-	delete(c.Parent.Connections, c.Address)
+
+	// Free the player slot
+	if c.reservation != nil {
+		c.pm.RemovePlayer(*c.reservation)
+	}
+	c.Parent.Connections.Remove(c.Address)
 }
 
 // Kick a connection with a given message
@@ -394,28 +315,50 @@ func (c *TCPConnection) Kick(msg string) {
 	c.Infof("Kicking connection %s", c.Address)
 	c.Infof("Reason: %s", msg)
 
-	c.Write(types.NewTcpPacket("K" + msg)) // Kick the connection
+	c.Write(protocol.Packet(&protocol.Kick{Reason: msg})) // Kick the connection
 	// c.Close()
 }
 
+// Disconnect kicks a connection with a given message, and closes it once the kick message has been sent.
+// The reader then stops, which cleans up the connection as usual.
+func (c *TCPConnection) Disconnect(reason string) {
+	c.Kick(reason)
+
+	c.StartWriter()
+	c.closer.Do(func() {
+		close(c.done)
+	})
+
+	go func() {
+		<-c.flushed
+		_ = c.Conn.Close()
+	}()
+}
+
 // Main gamemplay loop for the connection
 func (c *TCPConnection) RuntimeLoop() bool {
 	_ = c.Conn.SetReadDeadline(time.Now().Add(5 * time.Second))
 
-	packet, err := types.ReadTcpPacket(c.Conn)
+	packet, err := c.reader.ReadFrame()
 
 	if packet.IsEmpty() {
 		if err != nil {
 
+			var frameErr *types.FrameError
+			if errors.As(err, &frameErr) {
+				c.Error("Client sent an invalid frame - Additional output below")
+				c.Error(frameErr.Error())
+				return true
+			}
+
 			e := err.Error()
 
 			if strings.HasSuffix(e, "i/o timeout") {
 				c.Debug("I/O Timeout Err - Ignoring")
 				return false
 			} else if strings.HasSuffix(e, "EOF") {
-				// Sleep the thread for a second to allow the client to begin transmitting
-				time.Sleep(time.Second)
-				return false
+				c.Debug("Client closed the connection")
+				return true
 			} else {
 				c.Error("Error reading from connection - Additional output below")
 				c.Error(err.Error())
@@ -431,6 +374,9 @@ func (c *TCPConnection) RuntimeLoop() bool {
 	return false
 }
 
+// Route a gameplay message to the handler registered for its code
 func (c *TCPConnection) GameplayParser(Packet types.TcpPacket) {
 	c.Debugf("Received packet: %s", Packet.Data)
+
+	c.Parent.Dispatcher.Dispatch(c, Packet)
 }
@@ -0,0 +1,255 @@
+package tcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/player_manager"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// Used to give each test connection a unique address
+var testConnections int64
+
+// Create a TCP server for tests, which is not listening
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	config.Configuration.General.MaxPlayers = 4
+	config.Configuration.NetBeams.SendQueueSize = 16
+	config.Configuration.NetBeams.WriteTimeoutTime = time.Second
+
+	types.NewApplication()
+	types.App.AddService(player_manager.Service())
+
+	return Service()
+}
+
+// Create a connection backed by an in-memory pipe, returning the client end of the pipe
+func newTestConnection(t *testing.T, s *Server) (*TCPConnection, net.Conn) {
+	t.Helper()
+
+	return newTestConnectionFrom(t, s, fmt.Sprintf("pipe-%d", atomic.AddInt64(&testConnections, 1)))
+}
+
+// Create a connection backed by an in-memory pipe, which appears to come from the given address
+func newTestConnectionFrom(t *testing.T, s *Server, addr string) (*TCPConnection, net.Conn) {
+	t.Helper()
+
+	server, client := net.Pipe()
+
+	c := NewTCPConnection(server, addr, s)
+	s.Connections.Add(c)
+
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+
+		// Wait for the writer to stop, so it cannot outlive the test and race with the next one
+		c.StartWriter()
+		c.closer.Do(func() {
+			close(c.done)
+		})
+		<-c.flushed
+	})
+
+	return c, client
+}
+
+// Read a single frame sent to the client, failing the test if none arrives in time
+func readFrame(t *testing.T, reader *types.FrameReader, client net.Conn) types.TcpPacket {
+	t.Helper()
+
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	packet, err := reader.ReadFrame()
+
+	if err != nil {
+		t.Fatalf("Expected a frame, got %v", err)
+	}
+
+	return packet
+}
+
+// Read a single uncompressed frame without buffering, for streams which carry raw data after it
+func readUnbuffered(t *testing.T, client net.Conn) string {
+	t.Helper()
+
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	header := make([]byte, 4)
+
+	if _, err := io.ReadFull(client, header); err != nil {
+		t.Fatalf("Expected a frame, got %v", err)
+	}
+
+	data := make([]byte, binary.LittleEndian.Uint32(header))
+
+	if _, err := io.ReadFull(client, data); err != nil {
+		t.Fatalf("Expected a frame, got %v", err)
+	}
+
+	return string(data)
+}
+
+// Wait for a channel to be closed, failing the test if it takes too long
+func waitClosed(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for %s", what)
+	}
+}
+
+func TestSendQueueOverflowEvicts(t *testing.T) {
+	s := newTestServer(t)
+	config.Configuration.NetBeams.SendQueueSize = 2
+
+	// The writer is not started, so nothing drains the queue
+	c, client := newTestConnection(t, s)
+
+	if !c.enqueue([]byte("a")) || !c.enqueue([]byte("b")) {
+		t.Fatal("Expected the first frames to fit in the queue")
+	}
+
+	if c.enqueue([]byte("c")) {
+		t.Error("Expected a full queue to reject the frame")
+	}
+
+	waitClosed(t, c.done, "the connection to be evicted")
+
+	// The connection is closed straight away, without flushing the queue
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+
+	if _, err := client.Read(make([]byte, 1)); err == nil {
+		t.Error("Expected the evicted connection to be closed")
+	}
+
+	if c.enqueue([]byte("d")) {
+		t.Error("Expected an evicted connection to reject further frames")
+	}
+}
+
+func TestCloseFlushesKick(t *testing.T) {
+	s := newTestServer(t)
+	c, client := newTestConnection(t, s)
+	c.StartWriter()
+
+	c.Write(types.NewTcpPacket("hello"))
+
+	closed := make(chan struct{})
+	go func() {
+		c.Close()
+		close(closed)
+	}()
+
+	reader := types.NewFrameReader(client, types.MaxHeaderSize)
+
+	if packet := readFrame(t, reader, client); packet.String() != "hello" {
+		t.Errorf("Expected the queued frame first, got %q", packet.String())
+	}
+
+	if packet := readFrame(t, reader, client); packet.Code(0) != 'K' {
+		t.Errorf("Expected a kick frame, got %q", packet.String())
+	}
+
+	waitClosed(t, closed, "the connection to close")
+
+	if s.Connections.Get(c.Address) != nil {
+		t.Error("Expected the connection to be removed from the registry")
+	}
+}
+
+func TestCloseWithoutWriter(t *testing.T) {
+	s := newTestServer(t)
+
+	// Connections which never reach Listen have no writer running
+	c, client := newTestConnection(t, s)
+
+	closed := make(chan struct{})
+	go func() {
+		c.Close()
+		close(closed)
+	}()
+
+	reader := types.NewFrameReader(client, types.MaxHeaderSize)
+
+	if packet := readFrame(t, reader, client); packet.Code(0) != 'K' {
+		t.Errorf("Expected a kick frame, got %q", packet.String())
+	}
+
+	waitClosed(t, closed, "the connection to close")
+	waitClosed(t, c.flushed, "the writer to exit")
+}
+
+func TestDisconnectClosesAfterKick(t *testing.T) {
+	s := newTestServer(t)
+	c, client := newTestConnection(t, s)
+
+	c.Disconnect("Goodbye")
+
+	reader := types.NewFrameReader(client, types.MaxHeaderSize)
+
+	if packet := readFrame(t, reader, client); packet.String() != "KGoodbye" {
+		t.Errorf("Expected the kick frame, got %q", packet.String())
+	}
+
+	waitClosed(t, c.flushed, "the writer to exit")
+
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+
+	if _, err := client.Read(make([]byte, 1)); err == nil {
+		t.Error("Expected the connection to be closed after the kick")
+	}
+}
+
+func TestWriteLoopPrioritisesPackets(t *testing.T) {
+	s := newTestServer(t)
+	c, client := newTestConnection(t, s)
+
+	// Queue bulk data first, then packets, before the writer starts
+	queued := make(chan bool, 1)
+	go func() {
+		queued <- c.WriteRaw([]byte("bulk-one")) && c.WriteRaw([]byte("bulk-two"))
+	}()
+
+	select {
+	case ok := <-queued:
+		if !ok {
+			t.Fatal("Expected the bulk data to be queued")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the bulk data to be queued without waiting for the writer")
+	}
+
+	c.Write(types.NewTcpPacket("first"))
+	c.Write(types.NewTcpPacket("second"))
+
+	c.StartWriter()
+
+	for _, want := range []string{"first", "second"} {
+		if frame := readUnbuffered(t, client); frame != want {
+			t.Errorf("Expected %q before the bulk data, got %q", want, frame)
+		}
+	}
+
+	// Bulk data is written raw, without a frame header
+	raw := make([]byte, len("bulk-onebulk-two"))
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	if _, err := io.ReadFull(client, raw); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(raw) != "bulk-onebulk-two" {
+		t.Errorf("Expected the bulk data in order, got %q", raw)
+	}
+}
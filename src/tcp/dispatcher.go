@@ -0,0 +1,127 @@
+package tcp
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/altriusrs/netbeams/src/logs"
+	"github.com/altriusrs/netbeams/src/protocol"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// A Handler processes a single gameplay message received from a playing connection
+type Handler func(c *TCPConnection, packet types.TcpPacket)
+
+// Dispatcher routes gameplay messages to handlers based on their code (the first byte of the message)
+type Dispatcher struct {
+	logs.Logger
+	mutex    sync.RWMutex
+	handlers map[byte]Handler // Handlers keyed by message code
+	unknown  map[byte]uint64  // The number of messages received for each unknown code
+}
+
+// Create a new dispatcher with the built in handlers registered
+func NewDispatcher() *Dispatcher {
+	d := &Dispatcher{
+		Logger:   logs.NetLogger("Dispatcher"),
+		handlers: make(map[byte]Handler),
+		unknown:  make(map[byte]uint64),
+	}
+
+	d.Register('p', handlePing)
+	d.Register('C', handleUnimplemented("chat"))
+	d.Register('O', handleUnimplemented("vehicle"))
+	d.Register('E', handleEvent)
+
+	return d
+}
+
+// Register a handler for a message code, replacing any handler already registered for it
+func (d *Dispatcher) Register(code byte, handler Handler) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, ok := d.handlers[code]; ok {
+		d.Debugf("Replacing handler for message code %q", code)
+	}
+
+	d.handlers[code] = handler
+}
+
+// Remove the handler for a message code
+func (d *Dispatcher) Unregister(code byte) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	delete(d.handlers, code)
+}
+
+// Dispatch a message to the handler registered for its code
+func (d *Dispatcher) Dispatch(c *TCPConnection, packet types.TcpPacket) {
+	if len(packet.Data) == 0 {
+		return
+	}
+
+	code := packet.Data[0]
+
+	d.mutex.RLock()
+	handler, ok := d.handlers[code]
+	d.mutex.RUnlock()
+
+	if !ok {
+		d.mutex.Lock()
+		d.unknown[code]++
+		count := d.unknown[code]
+		d.mutex.Unlock()
+
+		c.Warnf("Received message with unknown code %q (%d received so far)", code, count)
+		return
+	}
+
+	// A misbehaving handler should not take the connection down with it
+	defer func() {
+		if r := recover(); r != nil {
+			c.Errorf("Handler for message code %q failed - Additional output below", code)
+			c.Fatal(fmt.Errorf("%v", r))
+		}
+	}()
+
+	handler(c, packet)
+}
+
+// UnknownCounts returns the number of messages received for each unknown code
+func (d *Dispatcher) UnknownCounts() map[byte]uint64 {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	counts := make(map[byte]uint64, len(d.unknown))
+	for code, count := range d.unknown {
+		counts[code] = count
+	}
+
+	return counts
+}
+
+// Answer TCP pings from the client
+func handlePing(c *TCPConnection, packet types.TcpPacket) {
+	c.Write(types.NewTcpPacket("p"))
+}
+
+// Decode and log events sent by the client
+func handleEvent(c *TCPConnection, packet types.TcpPacket) {
+	var event protocol.Event
+
+	if err := protocol.Decode(packet, &event); err != nil {
+		c.Warn(err.Error())
+		return
+	}
+
+	c.Debugf("Received event %s: %s", event.Name, event.Data)
+}
+
+// A placeholder for known message codes which have no feature registered to handle them yet
+func handleUnimplemented(name string) Handler {
+	return func(c *TCPConnection, packet types.TcpPacket) {
+		c.Debugf("Ignoring %s message - No handler registered", name)
+	}
+}
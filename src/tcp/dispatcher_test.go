@@ -0,0 +1,80 @@
+package tcp
+
+import (
+	"testing"
+
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+func TestDispatcherRoutesByCode(t *testing.T) {
+	s := newTestServer(t)
+	c, _ := newTestConnection(t, s)
+	d := NewDispatcher()
+
+	received := ""
+	d.Register('X', func(c *TCPConnection, packet types.TcpPacket) {
+		received = packet.String()
+	})
+
+	d.Dispatch(c, types.NewTcpPacket("Xhello"))
+
+	if received != "Xhello" {
+		t.Errorf("Expected the handler to receive the packet, got %q", received)
+	}
+
+	d.Unregister('X')
+	received = ""
+	d.Dispatch(c, types.NewTcpPacket("Xagain"))
+
+	if received != "" {
+		t.Error("Expected an unregistered handler not to be called")
+	}
+}
+
+func TestDispatcherRecoversFromPanics(t *testing.T) {
+	s := newTestServer(t)
+	c, _ := newTestConnection(t, s)
+	d := NewDispatcher()
+
+	d.Register('X', func(c *TCPConnection, packet types.TcpPacket) {
+		panic("broken handler")
+	})
+
+	// The panic must not escape the dispatcher
+	d.Dispatch(c, types.NewTcpPacket("X"))
+
+	calls := 0
+	d.Register('Y', func(c *TCPConnection, packet types.TcpPacket) {
+		calls++
+	})
+
+	d.Dispatch(c, types.NewTcpPacket("Y"))
+
+	if calls != 1 {
+		t.Errorf("Expected the dispatcher to keep working after a panic, got %d calls", calls)
+	}
+}
+
+func TestDispatcherCountsUnknownCodes(t *testing.T) {
+	s := newTestServer(t)
+	c, _ := newTestConnection(t, s)
+	d := NewDispatcher()
+
+	d.Dispatch(c, types.NewTcpPacket("Zone"))
+	d.Dispatch(c, types.NewTcpPacket("Ztwo"))
+	d.Dispatch(c, types.NewTcpPacket("Qone"))
+	d.Dispatch(c, types.NewTcpPacket(""))
+
+	counts := d.UnknownCounts()
+
+	if counts['Z'] != 2 || counts['Q'] != 1 || len(counts) != 2 {
+		t.Errorf("Unexpected unknown code counts: %v", counts)
+	}
+
+	// The counts returned are a copy
+	counts['Z'] = 100
+
+	if d.UnknownCounts()['Z'] != 2 {
+		t.Error("Expected the unknown code counts to be copied")
+	}
+}
@@ -0,0 +1,215 @@
+package tcp
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/mods"
+)
+
+// How long a file request waits for the client to open its download socket
+const DownloadAttachTimeout = 10 * time.Second
+
+// How long a transfer may make no progress before it is abandoned
+const DownloadIdleTimeout = 30 * time.Second
+
+// The amount of data written to the download socket between deadline and bandwidth checks
+const DownloadChunkSize = 256 * 1024
+
+// How often the progress of a transfer is logged
+const DownloadProgressInterval = 5 * time.Second
+
+var ErrNoDownloadSocket = errors.New("the client did not open a download socket")
+
+// ServeDownload attaches a download (D) connection to the main session of its player.
+// The client sends its player ID as a single byte, after which the connection only carries raw file data.
+func (c *TCPConnection) ServeDownload() {
+	c.download = true
+
+	id, err := c.reader.ReadByte()
+
+	if err != nil {
+		c.Error("Error reading player ID for download - Additional output below")
+		c.Error(err.Error())
+		return
+	}
+
+	session := c.Parent.Connections.GetByPlayerId(int(id))
+
+	if session == nil || throttleKey(session.Address) != throttleKey(c.Address) {
+		c.Warnf("Rejected download connection - No session for player %d from this address", id)
+		return
+	}
+
+	select {
+	case session.downloads <- c:
+		c.Debugf("Attached download connection to player %d", id)
+	default:
+		c.Warnf("Rejected download connection - Player %d already has a download connection", id)
+		return
+	}
+
+	// The session writes to this connection directly, so it only has to stay open until the session is finished with it
+	<-c.done
+}
+
+// Wait for the client to open its download socket
+func (c *TCPConnection) downloadSocket() (*TCPConnection, error) {
+	if c.downloadConn != nil {
+		return c.downloadConn, nil
+	}
+
+	select {
+	case d := <-c.downloads:
+		c.downloadConn = d
+		return d, nil
+	case <-c.done:
+		return nil, ErrNoDownloadSocket
+	case <-time.After(DownloadAttachTimeout):
+		return nil, ErrNoDownloadSocket
+	}
+}
+
+// Close the download socket, if the client opened one
+func (c *TCPConnection) closeDownloadSocket() {
+	select {
+	case d := <-c.downloads:
+		d.Evict("Session closed")
+	default:
+	}
+
+	if c.downloadConn != nil {
+		c.downloadConn.Evict("Session closed")
+		c.downloadConn = nil
+	}
+}
+
+// A file being sent to the client, split between the main and download sockets
+type transfer struct {
+	mod      mods.Mod
+	sent     int64 // The number of bytes sent so far, updated atomically
+	progress int64 // When progress was last logged, in Unix nanoseconds, updated atomically
+}
+
+// StreamMod sends a mod to the client as raw data.
+// The first half is sent over the main socket and the second half over the download socket, in parallel.
+func (c *TCPConnection) StreamMod(file *os.File, mod mods.Mod) error {
+	d, err := c.downloadSocket()
+
+	if err != nil {
+		return err
+	}
+
+	t := &transfer{mod: mod, progress: time.Now().UnixNano()}
+	half := mod.Size / 2
+
+	// The download socket needs its own file handle, as it reads from a different offset
+	second, err := os.Open(mod.Path)
+
+	if err != nil {
+		return err
+	}
+
+	defer second.Close()
+
+	if _, err = second.Seek(half, io.SeekStart); err != nil {
+		return err
+	}
+
+	result := make(chan error, 1)
+
+	go func() {
+		result <- c.streamDirect(d, second, mod.Size-half, t)
+	}()
+
+	err = c.streamQueued(file, half, t)
+
+	if err != nil {
+		// Stop the other half, rather than waiting for it to finish a transfer which has already failed
+		d.Evict("Transfer failed")
+	}
+
+	if downloadErr := <-result; err == nil {
+		err = downloadErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.Infof("Sent mod %s (%d bytes)", mod.Name, mod.Size)
+
+	return nil
+}
+
+// Send part of a file over the main socket, through its send queue
+func (c *TCPConnection) streamQueued(file *os.File, size int64, t *transfer) error {
+	remaining := size
+
+	for remaining > 0 {
+		chunk := make([]byte, minInt64(remaining, ModChunkSize))
+
+		if _, err := io.ReadFull(file, chunk); err != nil {
+			return err
+		}
+
+		if !c.waitForBandwidth(len(chunk)) || !c.WriteRaw(chunk) {
+			return io.ErrClosedPipe
+		}
+
+		remaining -= int64(len(chunk))
+		c.logProgress(t, int64(len(chunk)))
+	}
+
+	return nil
+}
+
+// Send part of a file over the download socket.
+// Copying from the file directly lets the kernel send it without copying it through the process (sendfile).
+func (c *TCPConnection) streamDirect(d *TCPConnection, file *os.File, size int64, t *transfer) error {
+	remaining := size
+
+	for remaining > 0 {
+		size := minInt64(remaining, DownloadChunkSize)
+
+		if !c.waitForBandwidth(int(size)) {
+			return io.ErrClosedPipe
+		}
+
+		_ = d.Conn.SetWriteDeadline(time.Now().Add(DownloadIdleTimeout))
+
+		written, err := io.CopyN(d.Conn, file, size)
+
+		remaining -= written
+		c.logProgress(t, written)
+
+		if err != nil {
+			d.Evict("Transfer failed")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Record the progress of a transfer, logging it at most once per interval
+func (c *TCPConnection) logProgress(t *transfer, written int64) {
+	c.downloadSent.Add(written)
+	c.Parent.DownloadSent.Add(written)
+
+	sent := atomic.AddInt64(&t.sent, written)
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&t.progress)
+
+	if now-last < int64(DownloadProgressInterval) || sent >= t.mod.Size {
+		return
+	}
+
+	// Only one of the two halves logs the progress of each interval
+	if atomic.CompareAndSwapInt64(&t.progress, last, now) {
+		c.Infof("Sending mod %s - %d%% (%d/%d bytes, %s)", t.mod.Name, sent*100/t.mod.Size, sent, t.mod.Size, FormatRate(c.downloadSent.Rate()))
+	}
+}
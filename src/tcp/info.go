@@ -0,0 +1,61 @@
+package tcp
+
+import (
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/environment"
+	"github.com/altriusrs/netbeams/src/player_manager"
+	"github.com/altriusrs/netbeams/src/protocol"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// ServerInfo describes the server, as sent to probe (P) connections
+func (s *Server) ServerInfo() protocol.ServerInfo {
+	general := config.Configuration.General
+
+	info := protocol.ServerInfo{
+		Name:          general.Name,
+		Map:           general.Map,
+		MaxPlayers:    general.MaxPlayers,
+		PlayerList:    []string{},
+		Tags:          general.Tags,
+		Description:   general.Description,
+		Port:          general.Port,
+		Private:       general.Private,
+		Guests:        config.Configuration.Auth.AllowGuests,
+		Version:       environment.Version,
+		ClientVersion: environment.MinProtocolVersion,
+	}
+
+	if pm, ok := types.App.GetService("Player Manager").(*player_manager.PlayerManager); ok {
+		list := BuildPlayerList(pm)
+		info.Players = list.Count
+		info.PlayerList = list.Names
+	}
+
+	if mm := modManager(); mm != nil {
+		for _, mod := range mm.Manifest() {
+			info.ModsTotal++
+			info.ModsTotalSize += mod.Size
+		}
+	}
+
+	return info
+}
+
+// Probe answers a probe (P) connection with the server info.
+// Monitoring tools may send PJ in a single write to receive the info as JSON instead.
+func (c *TCPConnection) Probe() {
+	info := c.Parent.ServerInfo()
+
+	// The J arrives with the P, so the client is never made to wait for a format which was not requested
+	if c.reader.Buffered() > 0 {
+		if format, err := c.reader.ReadByte(); err == nil && format == 'J' {
+			c.Debug("Sending server info as JSON")
+			c.Write(types.NewTcpPacket(info.EncodeJSON()))
+			return
+		}
+	}
+
+	c.Debug("Sending server info")
+	c.Write(protocol.Packet(&info))
+}
@@ -0,0 +1,54 @@
+package tcp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// Start a probe connection with the bytes sent by the client, returning the reply
+func probe(t *testing.T, s *Server, request string) string {
+	t.Helper()
+
+	c, client := newTestConnection(t, s)
+	c.StartWriter()
+
+	go func() {
+		// The state byte is read before the probe, as it is when the connection is accepted
+		if _, err := c.reader.ReadByte(); err == nil {
+			c.Probe()
+		}
+	}()
+
+	if _, err := client.Write([]byte(request)); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := types.NewFrameReader(client, types.MaxHeaderSize)
+
+	return string(readFrame(t, reader, client).Data)
+}
+
+func TestProbe(t *testing.T) {
+	s := newTestServer(t)
+	config.Configuration.General.Name = "Probe Server"
+
+	start := time.Now()
+	info := probe(t, s, "P")
+
+	if !strings.HasPrefix(info, "P") || !strings.Contains(info, "name=Probe+Server") {
+		t.Errorf("Expected the server info, got %q", info)
+	}
+
+	// Game clients only send the P, and should not wait for a format they never asked for
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("Expected the probe to be answered straight away, took %s", elapsed)
+	}
+
+	if info := probe(t, s, "PJ"); !strings.HasPrefix(info, "{") || !strings.Contains(info, `"name":"Probe Server"`) {
+		t.Errorf("Expected the server info as JSON, got %q", info)
+	}
+}
@@ -0,0 +1,71 @@
+package tcp
+
+import (
+	"github.com/altriusrs/netbeams/src/mods"
+	"github.com/altriusrs/netbeams/src/protocol"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// The size of each chunk of a mod file queued for sending
+const ModChunkSize = 64 * 1024
+
+// Get the Mod Manager service, if it is running
+func modManager() *mods.ModManager {
+	mm, _ := types.App.GetService("Mod Manager").(*mods.ModManager)
+	return mm
+}
+
+// SendModList answers the client's SR request with the mod manifest
+func (c *TCPConnection) SendModList() {
+	list := protocol.ModList{}
+
+	if mm := modManager(); mm != nil {
+		list = mm.ModList()
+	}
+
+	c.Debugf("Sending mod list (%d mods)", len(list.Files))
+	c.Write(protocol.Packet(&list))
+}
+
+// SendMod answers a file request from the client.
+// It returns false if the connection had to be dropped part way through the transfer.
+func (c *TCPConnection) SendMod(request protocol.ModRequest) bool {
+	mm := modManager()
+
+	if mm == nil {
+		c.Warnf("Unable to send mod %s - Mod Manager unavailable", request.Name)
+		c.Write(types.NewTcpPacket("CO"))
+		return true
+	}
+
+	file, mod, err := mm.Open(request.Name)
+
+	if err != nil {
+		c.Warnf("Unable to send mod %s - %s", request.Name, err.Error())
+		c.Write(types.NewTcpPacket("CO"))
+		return true
+	}
+
+	defer file.Close()
+
+	c.Infof("Sending mod %s (%d bytes)", mod.Name, mod.Size)
+	c.Write(types.NewTcpPacket("AG"))
+
+	// The file is sent as raw data, so once it has started the client expects every byte of it
+	if err = c.StreamMod(file, mod); err != nil {
+		c.Errorf("Unable to send mod %s - Additional output below", mod.Name)
+		c.Error(err.Error())
+		c.Evict("Mod transfer failed")
+		return false
+	}
+
+	return true
+}
+
+func minInt64(a int64, b int64) int64 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,154 @@
+package tcp
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/mods"
+	"github.com/altriusrs/netbeams/src/protocol"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// Start a Mod Manager serving the given client mods, keyed by file name
+func newTestModManager(t *testing.T, files map[string]string) *mods.ModManager {
+	t.Helper()
+
+	config.Configuration.General.ResourceFolder = t.TempDir()
+	folder := filepath.Join(config.Configuration.General.ResourceFolder, "Client")
+
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(folder, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mm := mods.Service()
+
+	if err := mm.StartService(); err != nil {
+		t.Fatal(err)
+	}
+
+	types.App.AddService(mm)
+
+	t.Cleanup(func() {
+		_ = types.App.RemoveService(mm.GetName())
+	})
+
+	return mm
+}
+
+func TestSendModList(t *testing.T) {
+	s := newTestServer(t)
+	newTestModManager(t, map[string]string{"b.zip": "bbbbbbbbbbbbbbbbbbbb", "a.zip": "aaaaaaaaaa", "notes.txt": "ignored"})
+
+	c, client := newTestConnection(t, s)
+	c.StartWriter()
+	c.SendModList()
+
+	packet := readFrame(t, types.NewFrameReader(client, types.MaxHeaderSize), client)
+
+	if packet.String() != "/a.zip;/b.zip;10;20;" {
+		t.Errorf("Unexpected mod list %q", packet.String())
+	}
+}
+
+func TestSendModListEmpty(t *testing.T) {
+	s := newTestServer(t)
+	_ = types.App.RemoveService("Mod Manager")
+
+	c, client := newTestConnection(t, s)
+	c.StartWriter()
+	c.SendModList()
+
+	if packet := readFrame(t, types.NewFrameReader(client, types.MaxHeaderSize), client); packet.String() != "-" {
+		t.Errorf("Expected an empty mod list, got %q", packet.String())
+	}
+}
+
+func TestSendModRejectsUnknownMods(t *testing.T) {
+	s := newTestServer(t)
+	newTestModManager(t, map[string]string{"a.zip": "aaaaaaaaaa"})
+
+	c, client := newTestConnection(t, s)
+	c.StartWriter()
+	reader := types.NewFrameReader(client, types.MaxHeaderSize)
+
+	for _, name := range []string{"/missing.zip", "/../ServerConfig.toml"} {
+		if !c.SendMod(protocol.ModRequest{Name: name}) {
+			t.Errorf("%s: expected the connection to stay open", name)
+		}
+
+		if packet := readFrame(t, reader, client); packet.String() != "CO" {
+			t.Errorf("%s: expected CO, got %q", name, packet.String())
+		}
+	}
+}
+
+func TestSendModStreamsBothHalves(t *testing.T) {
+	s := newTestServer(t)
+
+	// Large enough to be split into several chunks on the main socket
+	content := strings.Repeat("0123456789abcdef", 3*ModChunkSize/16+5)
+	newTestModManager(t, map[string]string{"a.zip": content})
+
+	c, client := newTestConnection(t, s)
+	c.StartWriter()
+
+	d, downloadClient := newTestConnection(t, s)
+	d.download = true
+	c.downloads <- d
+
+	half := len(content) / 2
+	received := make(chan string, 2)
+
+	// The first half arrives on the main socket after the AG reply, the second on the download socket
+	go func() {
+		header := make([]byte, 6)
+		_ = client.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		if _, err := io.ReadFull(client, header); err != nil || string(header[4:]) != "AG" {
+			received <- fmt.Sprintf("unexpected reply %q", header)
+			return
+		}
+
+		first := make([]byte, half)
+		if _, err := io.ReadFull(client, first); err != nil {
+			received <- err.Error()
+			return
+		}
+
+		received <- string(first)
+	}()
+
+	go func() {
+		_ = downloadClient.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		second := make([]byte, len(content)-half)
+		if _, err := io.ReadFull(downloadClient, second); err != nil {
+			received <- err.Error()
+			return
+		}
+
+		received <- string(second)
+	}()
+
+	if !c.SendMod(protocol.ModRequest{Name: "/a.zip"}) {
+		t.Fatal("Expected the transfer to succeed")
+	}
+
+	parts := []string{<-received, <-received}
+
+	if !(parts[0] == content[:half] && parts[1] == content[half:]) && !(parts[1] == content[:half] && parts[0] == content[half:]) {
+		t.Errorf("Expected both halves of the mod, got %d and %d bytes", len(parts[0]), len(parts[1]))
+	}
+}
@@ -0,0 +1,122 @@
+package tcp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/player_manager"
+	"github.com/altriusrs/netbeams/src/protocol"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// How often the player list is checked for changes
+const PlayerListInterval = time.Second
+
+// How often the player list is sent to a client, even if it has not changed
+const PlayerListKeepAlive = 10 * time.Second
+
+// The last player list sent to a connection
+type playerListState struct {
+	list string    // The encoded list
+	sent time.Time // When it was sent
+}
+
+// BuildPlayerList builds the player list from the players known to the Player Manager.
+// Players with the HideName permission are counted, but their names are not listed.
+func BuildPlayerList(pm *player_manager.PlayerManager) protocol.PlayerList {
+	return buildPlayerList(pm, func(player *types.Player) string {
+		return player.DisplayName
+	})
+}
+
+// BuildGamePlayerList builds the player list shown in game, which includes the ping of each player if enabled
+func (s *Server) BuildGamePlayerList(pm *player_manager.PlayerManager) protocol.PlayerList {
+	if !config.Configuration.NetBeams.ShowPing {
+		return BuildPlayerList(pm)
+	}
+
+	return buildPlayerList(pm, func(player *types.Player) string {
+		// The latency is measured on the connection, the Player Manager only knows who the player is
+		c := s.Connections.GetByPlayerId(player.PlayerId)
+
+		if c == nil {
+			return player.DisplayName
+		}
+
+		if latency := c.GetPlayer().Latency; latency.Samples > 0 {
+			return fmt.Sprintf("%s (%dms)", player.DisplayName, latency.RTT.Milliseconds())
+		}
+
+		return player.DisplayName
+	})
+}
+
+// Build the player list, naming each listed player with the given function
+func buildPlayerList(pm *player_manager.PlayerManager, name func(player *types.Player) string) protocol.PlayerList {
+	players := pm.GetPlayers()
+
+	list := protocol.PlayerList{
+		Count: len(players),
+		Max:   config.Configuration.General.MaxPlayers,
+		Names: make([]string, 0, len(players)),
+	}
+
+	for _, player := range players {
+		if player.Permissions.HideName {
+			continue
+		}
+
+		list.Names = append(list.Names, name(player))
+	}
+
+	return list
+}
+
+// PlayerListLoop pushes the player list to every playing connection whenever it changes,
+// or when a keep-alive is due, until the server stops
+func (s *Server) PlayerListLoop() {
+	pm, ok := types.App.GetService("Player Manager").(*player_manager.PlayerManager)
+
+	if !ok {
+		s.Error("Player Manager unavailable - The player list will not be sent")
+		return
+	}
+
+	ticker := time.NewTicker(PlayerListInterval)
+	defer ticker.Stop()
+
+	sent := map[*TCPConnection]playerListState{}
+
+	for range ticker.C {
+		if *s.Status != types.StatusHealthy {
+			return
+		}
+
+		list := s.BuildGamePlayerList(pm)
+		encoded := string(list.Encode())
+		packet := protocol.Packet(&list)
+		now := time.Now()
+
+		current := map[*TCPConnection]playerListState{}
+
+		for _, c := range s.Connections.Snapshot() {
+			if c.GetState() != types.StatePlaying {
+				continue
+			}
+
+			last, ok := sent[c]
+
+			if ok && last.list == encoded && now.Sub(last.sent) < PlayerListKeepAlive {
+				current[c] = last
+				continue
+			}
+
+			c.Write(packet)
+			current[c] = playerListState{list: encoded, sent: now}
+		}
+
+		// Connections which have left are dropped from the map here
+		sent = current
+	}
+}
@@ -0,0 +1,74 @@
+package tcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/player_manager"
+	"github.com/altriusrs/netbeams/src/protocol"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// Encode a player list as it is sent to the client
+func encodeList(list protocol.PlayerList) string {
+	return string(list.Encode())
+}
+
+// Add a player to the Player Manager and give them a connection
+func addTestPlayer(t *testing.T, s *Server, pm *player_manager.PlayerManager, player types.Player) *TCPConnection {
+	t.Helper()
+
+	id, err := pm.ReserveSlotForConnection(nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	player.PlayerId = *id
+	player.Account = &types.Account{Name: player.DisplayName}
+
+	if _, err = pm.AddPlayer(&player, *id); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := newTestConnection(t, s)
+	c.SetPlayer(player)
+
+	return c
+}
+
+func TestBuildGamePlayerList(t *testing.T) {
+	s := newTestServer(t)
+	pm := types.App.GetService("Player Manager").(*player_manager.PlayerManager)
+
+	measured := addTestPlayer(t, s, pm, types.Player{DisplayName: "measured"})
+	addTestPlayer(t, s, pm, types.Player{DisplayName: "unmeasured"})
+
+	hidden := types.Player{DisplayName: "hidden"}
+	hidden.Permissions.HideName = true
+	addTestPlayer(t, s, pm, hidden)
+
+	measured.UpdatePlayer(func(p *types.Player) {
+		p.Latency.Sample(42*time.Millisecond, time.Now())
+	})
+
+	config.Configuration.NetBeams.ShowPing = true
+
+	if got, want := encodeList(s.BuildGamePlayerList(pm)), "Ss3/4:measured (42ms),unmeasured"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	config.Configuration.NetBeams.ShowPing = false
+
+	if got, want := encodeList(s.BuildGamePlayerList(pm)), "Ss3/4:measured,unmeasured"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// The server info never includes the ping
+	config.Configuration.NetBeams.ShowPing = true
+
+	if got, want := encodeList(BuildPlayerList(pm)), "Ss3/4:measured,unmeasured"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,198 @@
+package tcp
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/altriusrs/netbeams/src/protocol"
+)
+
+// A ProtocolHandler runs the session of a client, from the point its version has been accepted
+type ProtocolHandler interface {
+	// The name of the protocol, used for logging
+	Name() string
+
+	// Serve the connection until the session is over
+	Serve(c *TCPConnection, version *semver.Version)
+}
+
+// A protocol handler, and the client versions it is used for
+type protocolEntry struct {
+	versions   string              // The version constraint, as registered
+	constraint *semver.Constraints // The parsed version constraint
+	lower      *semver.Constraints // The lower bounds of the constraint, or nil if it has none
+	upper      *semver.Constraints // The upper bounds of the constraint, or nil if it has none
+	handler    ProtocolHandler
+}
+
+// ProtocolRegistry selects the protocol handler for a client based on its version
+type ProtocolRegistry struct {
+	mutex   sync.RWMutex
+	entries []protocolEntry // Handlers, in the order they were registered
+}
+
+// Create a new protocol registry with the built in protocols registered
+func NewProtocolRegistry() *ProtocolRegistry {
+	r := &ProtocolRegistry{}
+
+	if err := r.Register(VC2Versions, &VC2{}); err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+// Register a handler for the client versions matching a semver constraint (eg. ">= 2.0.0, < 3.0.0").
+// When the ranges of handlers overlap, the handler registered first is used.
+func (r *ProtocolRegistry) Register(versions string, handler ProtocolHandler) error {
+	constraint, err := semver.NewConstraint(versions)
+
+	if err != nil {
+		return fmt.Errorf("invalid version range %q for protocol %s: %w", versions, handler.Name(), err)
+	}
+
+	lower, upper := bounds(versions)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.entries = append(r.entries, protocolEntry{versions: versions, constraint: constraint, lower: lower, upper: upper, handler: handler})
+
+	return nil
+}
+
+// Split a version constraint into its lower and upper bounds, so that unsupported versions can be explained.
+// Only ranges made of comparisons (eg. ">= 2.0.0, < 3.0.0") have bounds.
+func bounds(versions string) (*semver.Constraints, *semver.Constraints) {
+	if strings.Contains(versions, "||") {
+		return nil, nil
+	}
+
+	lower := []string{}
+	upper := []string{}
+
+	for _, term := range strings.Split(versions, ",") {
+		term = strings.TrimSpace(term)
+
+		switch {
+		case strings.HasPrefix(term, ">"):
+			lower = append(lower, term)
+		case strings.HasPrefix(term, "<"):
+			upper = append(upper, term)
+		}
+	}
+
+	parse := func(terms []string) *semver.Constraints {
+		if len(terms) == 0 {
+			return nil
+		}
+
+		constraint, err := semver.NewConstraint(strings.Join(terms, ", "))
+
+		if err != nil {
+			return nil
+		}
+
+		return constraint
+	}
+
+	return parse(lower), parse(upper)
+}
+
+// Select the handler for a client version
+func (r *ProtocolRegistry) Select(version *semver.Version) (ProtocolHandler, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, entry := range r.entries {
+		if entry.constraint.Check(version) {
+			return entry.handler, true
+		}
+	}
+
+	return nil, false
+}
+
+// Unsupported explains why no handler accepts a client version.
+// A version is too old or too new when it falls below or above the range of every registered handler.
+func (r *ProtocolRegistry) Unsupported(version *semver.Version) string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	tooOld, tooNew := len(r.entries) > 0, len(r.entries) > 0
+
+	for _, entry := range r.entries {
+		tooOld = tooOld && entry.lower != nil && !entry.lower.Check(version)
+		tooNew = tooNew && entry.upper != nil && !entry.upper.Check(version)
+	}
+
+	switch {
+	case tooOld:
+		return "Client version is too old"
+	case tooNew:
+		return "Client version is too new"
+	default:
+		return "Client version is not supported"
+	}
+}
+
+// Versions returns the version ranges of the registered handlers, keyed by protocol name
+func (r *ProtocolRegistry) Versions() map[string]string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	versions := make(map[string]string, len(r.entries))
+	for _, entry := range r.entries {
+		versions[entry.handler.Name()] = entry.versions
+	}
+
+	return versions
+}
+
+// Negotiate reads the version of the client and hands the connection to the matching protocol handler
+func (c *TCPConnection) Negotiate() {
+	packet, err := c.reader.ReadFrame()
+
+	if err != nil {
+		c.Kick("Unable to read data")
+		c.Error("Error authenticating - Additional output below")
+		c.Fatal(err)
+		return
+	}
+
+	var clientVersion protocol.Version
+
+	if err = protocol.Decode(packet, &clientVersion); err != nil {
+		c.Kick("Unable to parse version")
+		c.Error("Error authenticating - Additional output below")
+		c.Fatal(err)
+		return
+	}
+
+	// Parse the version provided by the client
+	version, err := semver.NewVersion(clientVersion.Version)
+
+	if err != nil {
+		c.Kick("Unable to parse version")
+		c.Error("Error authenticating - Additional output below")
+		c.Fatal(err)
+		c.Error(clientVersion.Version)
+		return
+	}
+
+	// The registered protocols decide which client versions are supported
+	handler, ok := c.Parent.Protocols.Select(version)
+
+	if !ok {
+		reason := c.Parent.Protocols.Unsupported(version)
+		c.Kick(reason)
+		c.Errorf("Error authenticating - %s (%s), supported versions are %v", reason, version, c.Parent.Protocols.Versions())
+		return
+	}
+
+	c.Debugf("Client version: %s - Using protocol %s", version, handler.Name())
+
+	handler.Serve(c, version)
+}
@@ -0,0 +1,145 @@
+package tcp
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+type testProtocol struct {
+	name   string
+	served chan string // Receives the versions the protocol serves, if set
+}
+
+func (p *testProtocol) Name() string {
+	return p.name
+}
+
+func (p *testProtocol) Serve(c *TCPConnection, version *semver.Version) {
+	if p.served != nil {
+		p.served <- version.String()
+	}
+}
+
+func TestProtocolRegistrySelect(t *testing.T) {
+	r := NewProtocolRegistry()
+
+	if err := r.Register(">= 3.0.0, < 4.0.0", &testProtocol{name: "VC3"}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"2.0", "VC2"},
+		{"2.7.1", "VC2"},
+		{"3.0.0", "VC3"},
+		{"3.4.2", "VC3"},
+		{"1.9.9", ""},
+		{"4.0.0", ""},
+	}
+
+	for _, test := range tests {
+		handler, ok := r.Select(semver.MustParse(test.version))
+
+		if test.want == "" {
+			if ok {
+				t.Errorf("%s: expected no protocol, got %s", test.version, handler.Name())
+			}
+			continue
+		}
+
+		if !ok || handler.Name() != test.want {
+			t.Errorf("%s: expected %s, got %v", test.version, test.want, handler)
+		}
+	}
+}
+
+func TestProtocolRegistryInvalidRange(t *testing.T) {
+	r := NewProtocolRegistry()
+
+	if err := r.Register("not a range", &testProtocol{name: "Broken"}); err == nil {
+		t.Error("Expected an invalid version range to be rejected")
+	}
+}
+
+func TestProtocolRegistryUnsupported(t *testing.T) {
+	r := NewProtocolRegistry()
+
+	if err := r.Register(">= 5.0.0, < 6.0.0", &testProtocol{name: "VC5"}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"1.9.9", "Client version is too old"},
+		{"3.0.0", "Client version is not supported"},
+		{"6.0.0", "Client version is too new"},
+	}
+
+	for _, test := range tests {
+		if reason := r.Unsupported(semver.MustParse(test.version)); reason != test.want {
+			t.Errorf("%s: expected %q, got %q", test.version, test.want, reason)
+		}
+	}
+}
+
+func TestNegotiateUsesRegisteredProtocols(t *testing.T) {
+	s := newTestServer(t)
+	served := make(chan string, 1)
+
+	if err := s.Protocols.Register(">= 3.0.0, < 4.0.0", &testProtocol{name: "VC3", served: served}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tests := []struct {
+		version string
+		kick    string // The kick sent to the client, or empty if the version is served
+	}{
+		{"3.1.0", ""},
+		{"1.5.0", "KClient version is too old"},
+		{"4.0.0", "KClient version is too new"},
+	}
+
+	for _, test := range tests {
+		c, client := newTestConnection(t, s)
+		c.StartWriter()
+
+		negotiated := make(chan struct{})
+		go func() {
+			c.Negotiate()
+			close(negotiated)
+		}()
+
+		packet := types.NewTcpPacket("VC" + test.version)
+		if _, err := client.Write(packet.Serialize()); err != nil {
+			t.Fatal(err)
+		}
+
+		if test.kick == "" {
+			waitClosed(t, negotiated, "negotiation")
+
+			select {
+			case version := <-served:
+				if version != test.version {
+					t.Errorf("%s: expected the protocol to serve %s, got %s", test.version, test.version, version)
+				}
+			default:
+				t.Errorf("%s: expected the registered protocol to serve the client", test.version)
+			}
+			continue
+		}
+
+		reader := types.NewFrameReader(client, types.MaxHeaderSize)
+
+		if kick := readFrame(t, reader, client); string(kick.Data) != test.kick {
+			t.Errorf("%s: expected %q, got %q", test.version, test.kick, kick.Data)
+		}
+
+		waitClosed(t, negotiated, "negotiation")
+	}
+}
@@ -0,0 +1,25 @@
+package tcp
+
+import (
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+)
+
+// The message shown to players who are kicked for running out of online time
+const QuotaKickReason = "Your online time has run out"
+
+// HandleQuota kicks a player once they have run out of online time, banning them for the online kick duration
+func (s *Server) HandleQuota(id int, remaining time.Duration) {
+	if remaining > 0 {
+		return
+	}
+
+	c := s.Connections.GetByPlayerId(id)
+
+	if c == nil {
+		return
+	}
+
+	c.KickWithBan(QuotaKickReason, config.Configuration.Auth.Kick.OnlineDurationTime, "Online quota")
+}
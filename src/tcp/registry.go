@@ -0,0 +1,155 @@
+package tcp
+
+import (
+	"sync"
+
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// A callback fired when a connection is added to or removed from the registry
+type ConnectionEvent func(c *TCPConnection)
+
+// Registry is a concurrency safe collection of the connections held by the TCP server
+type Registry struct {
+	mutex       sync.RWMutex
+	connections map[string]*TCPConnection // Connections keyed by their remote address
+	onAdd       []ConnectionEvent         // Callbacks fired when a connection is added
+	onRemove    []ConnectionEvent         // Callbacks fired when a connection is removed
+}
+
+// Create a new, empty connection registry
+func NewRegistry() *Registry {
+	return &Registry{
+		connections: make(map[string]*TCPConnection),
+	}
+}
+
+// Register a callback which is fired whenever a connection is added
+func (r *Registry) OnAdd(event ConnectionEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.onAdd = append(r.onAdd, event)
+}
+
+// Register a callback which is fired whenever a connection is removed
+func (r *Registry) OnRemove(event ConnectionEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.onRemove = append(r.onRemove, event)
+}
+
+// Add a connection to the registry
+func (r *Registry) Add(c *TCPConnection) {
+	r.mutex.Lock()
+	r.connections[c.Address] = c
+	events := r.onAdd
+	r.mutex.Unlock()
+
+	for _, event := range events {
+		event(c)
+	}
+}
+
+// Remove a connection from the registry by its address.
+// Events are only fired if the connection was present.
+func (r *Registry) Remove(addr string) {
+	r.mutex.Lock()
+	c, ok := r.connections[addr]
+	delete(r.connections, addr)
+	events := r.onRemove
+	r.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	for _, event := range events {
+		event(c)
+	}
+}
+
+// Get a connection by its remote address
+func (r *Registry) Get(addr string) *TCPConnection {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.connections[addr]
+}
+
+// Get an authenticated connection by the ID of its player
+func (r *Registry) GetByPlayerId(id int) *TCPConnection {
+	return r.Find(func(c *TCPConnection) bool {
+		player := c.GetPlayer()
+		return player.Account != nil && player.PlayerId == id
+	})
+}
+
+// Get an authenticated connection by the BeamMP account ID of its player
+func (r *Registry) GetByAccount(id string) *TCPConnection {
+	return r.Find(func(c *TCPConnection) bool {
+		player := c.GetPlayer()
+		return player.Account != nil && player.Account.Id == id
+	})
+}
+
+// Find the first connection matching the filter
+func (r *Registry) Find(filter func(c *TCPConnection) bool) *TCPConnection {
+	for _, c := range r.Snapshot() {
+		if filter(c) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// Snapshot returns a copy of the connections currently held, which is safe to
+// iterate whilst connections join and leave
+func (r *Registry) Snapshot() []*TCPConnection {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	connections := make([]*TCPConnection, 0, len(r.connections))
+	for _, c := range r.connections {
+		connections = append(connections, c)
+	}
+
+	return connections
+}
+
+// Call fn for each connection, stopping early if fn returns false
+func (r *Registry) Each(fn func(c *TCPConnection) bool) {
+	for _, c := range r.Snapshot() {
+		if !fn(c) {
+			return
+		}
+	}
+}
+
+// Len returns the number of connections held
+func (r *Registry) Len() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return len(r.connections)
+}
+
+// Broadcast a packet to every playing connection, except the one given (which may be nil)
+func (r *Registry) Broadcast(packet types.TcpPacket, except *TCPConnection) {
+	r.BroadcastFilter(packet, func(c *TCPConnection) bool {
+		return c != except && c.GetState() == types.StatePlaying
+	})
+}
+
+// Broadcast a packet to every connection which matches the filter
+func (r *Registry) BroadcastFilter(packet types.TcpPacket, filter func(c *TCPConnection) bool) {
+	frame := packet.Serialize()
+
+	for _, c := range r.Snapshot() {
+		if filter(c) {
+			c.enqueue(frame)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package tcp
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+func TestRegistryHooksUnderConcurrentUse(t *testing.T) {
+	s := newTestServer(t)
+	r := NewRegistry()
+
+	var added, removed int64
+	r.OnAdd(func(c *TCPConnection) { atomic.AddInt64(&added, 1) })
+	r.OnRemove(func(c *TCPConnection) { atomic.AddInt64(&removed, 1) })
+
+	connections := make([]*TCPConnection, 64)
+	for i := range connections {
+		connections[i], _ = newTestConnection(t, s)
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range connections {
+		wg.Add(1)
+		go func(c *TCPConnection) {
+			defer wg.Done()
+
+			r.Add(c)
+			r.Snapshot()
+			r.Remove(c.Address)
+
+			// Removing a connection twice must not fire the hooks again
+			r.Remove(c.Address)
+		}(c)
+	}
+	wg.Wait()
+
+	if added != int64(len(connections)) || removed != int64(len(connections)) {
+		t.Errorf("Expected %d add and remove events, got %d and %d", len(connections), added, removed)
+	}
+
+	if r.Len() != 0 {
+		t.Errorf("Expected the registry to be empty, got %d connections", r.Len())
+	}
+}
+
+func TestRegistryLookups(t *testing.T) {
+	s := newTestServer(t)
+	r := NewRegistry()
+
+	c, _ := newTestConnection(t, s)
+	c.SetPlayer(types.Player{PlayerId: 3, Account: &types.Account{Id: "1234"}})
+	r.Add(c)
+
+	// Connections which have not authenticated have no player to find
+	anonymous, _ := newTestConnection(t, s)
+	r.Add(anonymous)
+
+	if found := r.GetByPlayerId(3); found != c {
+		t.Errorf("Expected to find the connection by player ID, got %v", found)
+	}
+
+	if found := r.GetByAccount("1234"); found != c {
+		t.Errorf("Expected to find the connection by account, got %v", found)
+	}
+
+	if found := r.GetByPlayerId(0); found != nil {
+		t.Errorf("Expected unauthenticated connections to be skipped, got %v", found)
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/altriusrs/netbeams/src/bandwidth"
 	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/player_manager"
 	"github.com/altriusrs/netbeams/src/types"
 )
 
@@ -15,7 +17,13 @@ type Server struct {
 	Addr        string
 	Port        int
 	Listener    *net.TCPListener
-	Connections map[string]TCPConnection
+	Connections *Registry
+	Dispatcher  *Dispatcher
+	Protocols   *ProtocolRegistry // Protocol handlers, selected by client version
+	Passwords   *Throttle         // Failed password attempts, by IP address
+
+	DownloadRate *bandwidth.Bucket // Limits the rate mods are sent across all clients
+	DownloadSent *bandwidth.Meter  // Measures the rate mods are sent across all clients
 }
 
 func Service() *Server {
@@ -24,7 +32,13 @@ func Service() *Server {
 		Service:     types.SpinUp("TCP Server"),
 		Addr:        "0.0.0.0", // Listen on all interfaces
 		Port:        config.Configuration.General.Port,
-		Connections: make(map[string]TCPConnection),
+		Connections: NewRegistry(),
+		Dispatcher:  NewDispatcher(),
+		Protocols:   NewProtocolRegistry(),
+		Passwords:   NewThrottle(config.Configuration.NetBeams.PasswordAttempts, config.Configuration.NetBeams.PasswordLockoutTime),
+
+		DownloadRate: bandwidth.NewBucket(int64(config.Configuration.NetBeams.DownloadLimit) * 1024),
+		DownloadSent: bandwidth.NewMeter(),
 	}
 
 	server.RegisterServiceHooks(server.Start, server.Stop, nil)
@@ -53,7 +67,14 @@ func (s *Server) Start() (types.Status, error) {
 	s.Info("TCP Server started")
 	s.Listener = listener
 
+	// Kick players who run out of online time
+	if pm, ok := types.App.GetService("Player Manager").(*player_manager.PlayerManager); ok {
+		pm.OnQuota(s.HandleQuota)
+	}
+
 	go s.Listen()
+	go s.PlayerListLoop()
+	go s.DownloadStatsLoop()
 	return types.StatusHealthy, nil
 }
 
@@ -102,7 +123,7 @@ func (s *Server) Listen() {
 		s.Debugf("Incoming connection from %s", conn.RemoteAddr())
 		connection := NewTCPConnection(conn, addr, s)
 
-		s.Connections[addr] = connection
+		s.Connections.Add(connection)
 
 		go connection.Listen()
 	}
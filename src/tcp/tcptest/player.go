@@ -0,0 +1,94 @@
+package tcptest
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/player_manager"
+	"github.com/altriusrs/netbeams/src/tcp"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// A playing connection backed by an in-memory pipe, which records the frames sent to the client
+type Player struct {
+	Connection *tcp.TCPConnection // The server end of the connection
+	frames     chan string        // The frames sent to the client
+}
+
+// Create a TCP server for tests, which is not listening
+func NewServer(t *testing.T) *tcp.Server {
+	t.Helper()
+
+	config.Configuration.NetBeams.SendQueueSize = 16
+	config.Configuration.NetBeams.WriteTimeoutTime = time.Second
+
+	types.NewApplication()
+	types.App.AddService(player_manager.Service())
+
+	return tcp.Service()
+}
+
+// Add a playing connection with a USER account to the server
+func NewPlayer(t *testing.T, server *tcp.Server, id int, name string) *Player {
+	t.Helper()
+
+	conn, client := net.Pipe()
+
+	c := tcp.NewTCPConnection(conn, fmt.Sprintf("%s-%d", t.Name(), id), server)
+	c.SetPlayer(types.Player{PlayerId: id, DisplayName: name, Account: &types.Account{Name: name, Roles: types.ParseRoles("USER")}})
+	c.SetState(types.StatePlaying)
+	c.StartWriter()
+	server.Connections.Add(c)
+
+	p := &Player{Connection: c, frames: make(chan string, 16)}
+
+	go func() {
+		reader := types.NewFrameReader(client, types.MaxHeaderSize)
+
+		for {
+			packet, err := reader.ReadFrame()
+
+			if err != nil {
+				close(p.frames)
+				return
+			}
+
+			p.frames <- packet.String()
+		}
+	}()
+
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = conn.Close()
+	})
+
+	return p
+}
+
+// Expect checks the next frame sent to the player
+func (p *Player) Expect(t *testing.T, want string) {
+	t.Helper()
+
+	select {
+	case frame := <-p.frames:
+		if frame != want {
+			t.Errorf("Expected %q, got %q", want, frame)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("Timed out waiting for %q", want)
+	}
+}
+
+// ExpectNothing checks that nothing is sent to the player
+func (p *Player) ExpectNothing(t *testing.T) {
+	t.Helper()
+
+	select {
+	case frame := <-p.frames:
+		t.Errorf("Expected nothing, got %q", frame)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
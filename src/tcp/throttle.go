@@ -0,0 +1,105 @@
+package tcp
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// The failed attempts recorded for a single IP address
+type throttleEntry struct {
+	failures    int       // The number of failures since the last lockout
+	lockedUntil time.Time // When the current lockout ends
+	lastFailure time.Time // When the last failure happened
+}
+
+// Throttle tracks failed attempts per IP address, locking an address out after too many failures
+type Throttle struct {
+	mutex    sync.Mutex
+	attempts int                       // The number of failures allowed before a lockout
+	lockout  time.Duration             // How long a lockout lasts
+	entries  map[string]*throttleEntry // Entries keyed by IP address
+}
+
+// Create a new throttle which locks an address out for the given duration after the given number of failures
+func NewThrottle(attempts int, lockout time.Duration) *Throttle {
+	return &Throttle{
+		attempts: attempts,
+		lockout:  lockout,
+		entries:  make(map[string]*throttleEntry),
+	}
+}
+
+// Locked reports whether an address is locked out, and for how much longer
+func (t *Throttle) Locked(addr string) (bool, time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	entry, ok := t.entries[throttleKey(addr)]
+
+	if !ok {
+		return false, 0
+	}
+
+	remaining := time.Until(entry.lockedUntil)
+
+	return remaining > 0, remaining
+}
+
+// Fail records a failed attempt from an address, returning true if the address is now locked out
+func (t *Throttle) Fail(addr string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.prune()
+
+	key := throttleKey(addr)
+	entry, ok := t.entries[key]
+
+	if !ok {
+		entry = &throttleEntry{}
+		t.entries[key] = entry
+	}
+
+	entry.failures++
+	entry.lastFailure = time.Now()
+
+	if entry.failures < t.attempts {
+		return false
+	}
+
+	entry.failures = 0
+	entry.lockedUntil = entry.lastFailure.Add(t.lockout)
+
+	return true
+}
+
+// Succeed clears the failed attempts recorded for an address
+func (t *Throttle) Succeed(addr string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.entries, throttleKey(addr))
+}
+
+// Drop entries which are no longer locked out and have not failed recently
+func (t *Throttle) prune() {
+	now := time.Now()
+
+	for key, entry := range t.entries {
+		if now.After(entry.lockedUntil) && now.Sub(entry.lastFailure) > t.lockout {
+			delete(t.entries, key)
+		}
+	}
+}
+
+// Attempts are tracked by IP address, so the port is removed from connection addresses
+func throttleKey(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
@@ -0,0 +1,98 @@
+package tcp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottleLocksOutAfterAttempts(t *testing.T) {
+	throttle := NewThrottle(3, time.Minute)
+
+	for i := 1; i < 3; i++ {
+		if throttle.Fail("198.51.100.7:1000") {
+			t.Fatalf("Expected no lockout after %d failures", i)
+		}
+
+		if locked, _ := throttle.Locked("198.51.100.7:1000"); locked {
+			t.Fatalf("Expected the address not to be locked after %d failures", i)
+		}
+	}
+
+	if !throttle.Fail("198.51.100.7:1000") {
+		t.Fatal("Expected a lockout after 3 failures")
+	}
+
+	locked, remaining := throttle.Locked("198.51.100.7:1000")
+
+	if !locked {
+		t.Fatal("Expected the address to be locked out")
+	}
+
+	if remaining <= 0 || remaining > time.Minute {
+		t.Errorf("Expected up to a minute of lockout to remain, got %s", remaining)
+	}
+
+	if locked, _ := throttle.Locked("198.51.100.8:1000"); locked {
+		t.Error("Expected other addresses not to be locked out")
+	}
+}
+
+func TestThrottleLockoutExpires(t *testing.T) {
+	throttle := NewThrottle(1, 20*time.Millisecond)
+
+	if !throttle.Fail("198.51.100.7:1000") {
+		t.Fatal("Expected a lockout after 1 failure")
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if locked, remaining := throttle.Locked("198.51.100.7:1000"); locked {
+		t.Errorf("Expected the lockout to have expired, %s remains", remaining)
+	}
+}
+
+func TestThrottleSucceedClearsFailures(t *testing.T) {
+	throttle := NewThrottle(2, time.Minute)
+
+	throttle.Fail("198.51.100.7:1000")
+	throttle.Succeed("198.51.100.7:1000")
+
+	if throttle.Fail("198.51.100.7:1000") {
+		t.Error("Expected the failure before the success to be forgotten")
+	}
+
+	if !throttle.Fail("198.51.100.7:1000") {
+		t.Error("Expected a lockout after 2 failures since the success")
+	}
+}
+
+func TestThrottleIgnoresPorts(t *testing.T) {
+	throttle := NewThrottle(2, time.Minute)
+
+	// Reconnecting gives the client a new port, which must not reset its failures
+	throttle.Fail("198.51.100.7:1000")
+
+	if !throttle.Fail("198.51.100.7:2000") {
+		t.Error("Expected failures from different ports of the same address to count together")
+	}
+
+	if locked, _ := throttle.Locked("198.51.100.7:3000"); !locked {
+		t.Error("Expected every port of the address to be locked out")
+	}
+
+	tests := []struct {
+		addr string
+		key  string
+	}{
+		{"198.51.100.7:1000", "198.51.100.7"},
+		{"[2001:db8::1]:1000", "2001:db8::1"},
+		{"198.51.100.7", "198.51.100.7"},
+		{"pipe-1", "pipe-1"},
+	}
+
+	for _, test := range tests {
+		if key := throttleKey(test.addr); key != test.key {
+			t.Errorf("throttleKey(%q) = %q, want %q", test.addr, key, test.key)
+		}
+	}
+}
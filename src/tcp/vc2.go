@@ -0,0 +1,339 @@
+package tcp
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/altriusrs/netbeams/src/access"
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/crypto"
+	"github.com/altriusrs/netbeams/src/http"
+	"github.com/altriusrs/netbeams/src/protocol"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// The client versions handled by the VC2 protocol
+const VC2Versions = ">= 2.0.0, < 3.0.0"
+
+// How long the client has to reply to the password prompt
+const PasswordTimeout = time.Minute
+
+// VC2 implements the session flow used by BeamMP 2.x clients, as described in flow.md
+type VC2 struct{}
+
+func (p *VC2) Name() string {
+	return "VC2"
+}
+
+func (p *VC2) Serve(c *TCPConnection, version *semver.Version) {
+	c.Authenticate()
+
+	// Only authorized players may continue to the mod sync
+	if !c.authorized {
+		return
+	}
+
+	// Sync mod data and server info to the client
+	c.SyncModData()
+
+	for c.GetState() == types.StatePlaying {
+		breakLoop := c.RuntimeLoop()
+		if breakLoop {
+			c.Kick("Connection closed by server")
+			return
+		}
+	}
+}
+
+// Authenticate the player using the key sent by the client, after the version has been accepted
+func (c *TCPConnection) Authenticate() {
+	c.SetState(types.StateAuthenticate)
+
+	// The client version is valid, we can now read the authentication key
+	c.Write(types.NewTcpPacket("A"))
+
+	packet, err := c.reader.ReadFrame()
+
+	if err != nil {
+		c.Kick("Unable to read data")
+		c.Error("Error authenticating - Additional output below")
+		c.Fatal(err)
+		return
+	}
+
+	var key protocol.AuthKey
+
+	if err = protocol.Decode(packet, &key); err != nil {
+		c.Kick("Invalid authentication key")
+		c.Error("Error authenticating - Additional output below")
+		c.Fatal(err)
+		return
+	}
+
+	if (config.Configuration.Auth.Proxy.Enable || config.Configuration.Auth.VPN.Enable) && c.nc != nil {
+		asn, err := c.nc.Check(c.Address)
+
+		if err != nil {
+			c.Kick("Unable to get ASN information")
+			c.Error("Error authenticating - Additional output below")
+			c.Fatal(err)
+			return
+		}
+
+		c.Debugf("IsProxy: %s", strconv.Itoa(int(asn.IsProxy)))
+		c.Debugf("ProxyType: %s", asn.ProxyType)
+		c.Debugf("CountryShort: %s", asn.CountryShort)
+		c.Debugf("CountryLong: %s", asn.CountryLong)
+		c.Debugf("Region: %s", asn.Region)
+		c.Debugf("City: %s", asn.City)
+		c.Debugf("Isp: %s", asn.Isp)
+		c.Debugf("Domain: %s", asn.Domain)
+		c.Debugf("UsageType: %s", asn.UsageType)
+		c.Debugf("Asn: %s", asn.Asn)
+		c.Debugf("As: %s", asn.As)
+		c.Debugf("LastSeen: %s", asn.LastSeen)
+		c.Debugf("Threat: %s", asn.Threat)
+	}
+
+	if config.Configuration.General.Password != "" {
+		if locked, remaining := c.Parent.Passwords.Locked(c.Address); locked {
+			c.Kick(fmt.Sprintf("Too many incorrect passwords - Please try again in %s", remaining.Round(time.Second)))
+			c.Warnf("Rejected %s - Locked out after too many incorrect passwords", c.Address)
+			return
+		}
+	}
+
+	c.Debugf("Authentication key: %s", key.Key)
+
+	player, err := types.App.GetService("BeamMP API").(*http.API).AuthenticatePlayer(key.Key)
+
+	if err != nil {
+		c.Kick("Unable to authenticate player")
+		c.Error("Error authenticating - Additional output below")
+		c.Fatal(err)
+		return
+	}
+
+	if player == nil {
+		c.Kick("Unable to authenticate player")
+		c.Error("Error authenticating - Additional output below")
+		c.Fatal(err)
+		return
+	}
+
+	entity := player.IntoPlayerEntity()
+
+	c.Debugf("Player: %s", player.Name)
+	c.Debugf("UID: %s", player.Uid)
+	c.Debugf("Roles: %s", player.Roles)
+	c.Debugf("Identifiers: %s", player.Identifiers)
+	c.Debugf("Is Guest?: %t", player.Guest)
+	c.Infof("Changing logger ID to %s", player.Name)
+	c.Module = player.Name
+
+	// Players who are not allowed to join are turned away before they take up a slot.
+	// The checks run in order: block list, role rules, allow list, then bans. The allow list does not override the others.
+	al, hasLists := types.App.GetService("Access Lists").(*access.AccessService)
+
+	if hasLists && al.Blocked(entity.Account) {
+		c.Kick(config.Configuration.Auth.Messages.Blocked)
+		c.Warnf("Rejected %s - On the block list", player.Name)
+		return
+	}
+
+	if admitted, message := config.Admit(entity.Account); !admitted {
+		c.Kick(message)
+		c.Warnf("Rejected %s (roles %s) - %s", player.Name, types.FormatRoles(entity.Account.Roles), message)
+		return
+	}
+
+	if hasLists && !al.Allowed(entity.Account) {
+		c.Kick(config.Configuration.Auth.Messages.NotAllowed)
+		c.Warnf("Rejected %s - Not on the allow list", player.Name)
+		return
+	}
+
+	// Banned players are also turned away before they take up a slot.
+	// The server does not start without its bans, so players who cannot be checked (such as when valkey is unreachable) are turned away too.
+	ban, err := c.CheckBan(entity.Account)
+
+	if err != nil {
+		c.Kick("The server is experiencing an error - Please try again later")
+		c.Error("Error checking bans - Additional output below")
+		c.Error(err.Error())
+		return
+	}
+
+	if ban != nil {
+		c.Kick(ban.Message(time.Now()))
+		c.Warnf("Rejected %s - Banned by %s (ban %s)", player.Name, ban.Issuer, ban.Id)
+		return
+	}
+
+	pid, err := c.pm.ReserveSlotForConnection(nil)
+
+	if err != nil {
+		if err.Error() == "server is full" {
+			c.Kick("Server is full")
+			return
+		} else {
+			c.Kick("The server is experiencing an error - Please try again later")
+			c.Error("Error authenticating - Additional output below")
+			c.Error(err.Error())
+			return
+		}
+	}
+
+	c.reservation = pid // Save the reservation ID
+
+	entity.PlayerId = *pid
+	entity.Address = c.Conn.RemoteAddr()
+	entity.Permissions = config.PermissionsFor(entity.Account)
+	c.SetPlayer(entity)
+
+	if config.Configuration.General.Password != "" {
+		success := c.HandlePassword()
+
+		if !success {
+			c.Error("Error authenticating - Failed to send valid password")
+			return
+		}
+	}
+
+	if _, err = c.pm.AddPlayer(&entity, *pid); err != nil {
+		c.Kick("The server is experiencing an error - Please try again later")
+		c.Error("Error authenticating - Additional output below")
+		c.Error(err.Error())
+		return
+	}
+
+	c.authorized = true
+}
+
+// HandlePassword prompts the client for the server password and checks the reply
+func (c *TCPConnection) HandlePassword() bool {
+	c.SetState(types.StatePassword)
+
+	c.Debug("Sending password request")
+	c.Write(types.NewTcpPacket("S"))
+
+	// The password is typed by the player, so they are given longer than usual to reply
+	_ = c.Conn.SetReadDeadline(time.Now().Add(PasswordTimeout))
+	defer c.Conn.SetReadDeadline(time.Time{})
+
+	packet, err := c.reader.ReadFrame()
+
+	if err != nil {
+		c.Kick("Unable to read data")
+		c.Error("Error authenticating - Additional output below")
+		c.Fatal(err)
+		return false
+	}
+
+	if !crypto.ComparePassword(packet.String(), config.Configuration.General.Password) {
+		if c.Parent.Passwords.Fail(c.Address) {
+			c.Warnf("Locking out %s for %s - Too many incorrect passwords", c.Address, config.Configuration.NetBeams.PasswordLockout)
+		}
+
+		c.Kick("Incorrect password")
+		return false
+	}
+
+	c.Parent.Passwords.Succeed(c.Address)
+
+	c.SetState(types.StateAuthenticate)
+	return true
+}
+
+func (c *TCPConnection) SyncModData() {
+	c.Debug("Client is preparing to sync mod data")
+
+	c.SetState(types.StateDownload)
+
+	// Tell the client which player ID it has been assigned
+	c.Write(protocol.Packet(&protocol.PlayerId{Id: c.Player.PlayerId}))
+
+	pauseStart := time.Now()
+
+	for {
+		packet, err := c.reader.ReadFrame()
+		if err != nil {
+			if time.Since(pauseStart) > 5*time.Second {
+				c.Kick("Unable to read data")
+				c.Error("Error reading from connection - Additional output below")
+				c.Error(err.Error())
+				return
+			} else {
+				c.Kick("Unable to read data")
+				c.Error("Error reading from connection - Additional output below")
+				c.Error(err.Error())
+				return
+			}
+
+		}
+
+		c.Debugf("Received packet: %v", packet)
+
+		if packet.IsEmpty() {
+			c.Error("Failed to read packet from client - Malformed?")
+			break
+		} else if packet.Code(0) == 'f' {
+			// The client is requesting a file
+			var request protocol.ModRequest
+
+			if err = protocol.Decode(packet, &request); err != nil {
+				c.Warn(err.Error())
+				c.Write(types.NewTcpPacket("CO"))
+			} else if !c.SendMod(request) {
+				return
+			}
+		} else if packet.Code(0) == 'S' {
+			if packet.Code(1) == 'R' {
+				// the client is requesting mod data
+				c.SendModList()
+			} else {
+				c.Error("The client sent an unknown request.")
+				c.Kick("The client sent an unknown request.")
+				break
+			}
+		} else if packet.String() == "Done" {
+			c.Debug("Client mod list synced")
+			c.SetState(types.StateMapLoad)
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if c.State != types.StateMapLoad {
+		c.Kick("Unable to sync mod data")
+		return
+	}
+
+	c.Debug("Sending map files")
+
+	c.Write(protocol.Packet(&protocol.Map{Path: config.Configuration.General.Map}))
+
+	packet, err := c.reader.ReadFrame()
+
+	if err != nil {
+		c.Error("Error reading from connection - Additional output below")
+		c.Error(err.Error())
+		return
+	}
+
+	if packet.Code(0) == 'H' {
+		c.Info("Client is connected and loaded")
+
+		if _, err = c.pm.ReserveSlotForPlay(c.Player.PlayerId); err != nil {
+			c.Error("Unable to reserve slot for play - Additional output below")
+			c.Error(err.Error())
+			return
+		}
+
+		c.SetState(types.StatePlaying)
+	} else {
+		c.Warn("Client may not be loaded - Unrecognized map load response")
+	}
+}
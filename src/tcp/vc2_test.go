@@ -0,0 +1,331 @@
+package tcp
+
+import (
+	"encoding/json"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/access"
+	"github.com/altriusrs/netbeams/src/bans"
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/environment"
+	"github.com/altriusrs/netbeams/src/http"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// Start a BeamMP API service which authenticates the given players, keyed by their authentication key
+func newTestAPI(t *testing.T, players map[string]http.Player) {
+	t.Helper()
+
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		var body map[string]string
+
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(nethttp.StatusBadRequest)
+			return
+		}
+
+		player, ok := players[body["key"]]
+
+		if !ok {
+			w.WriteHeader(nethttp.StatusNotFound)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(player)
+	}))
+
+	// The development version string contains escape codes, which are not a valid User-Agent
+	version := environment.Context.Version
+	environment.Context.Version = "test"
+
+	api := http.Service()
+	api.BaseURL = server.URL
+	types.App.AddService(api)
+
+	t.Cleanup(func() {
+		_ = types.App.RemoveService(api.GetName())
+		server.Close()
+		environment.Context.Version = version
+	})
+}
+
+// Start the access list service, using only the players in the config
+func newTestAccessLists(t *testing.T) {
+	t.Helper()
+
+	config.Configuration.Auth.AllowList.File = ""
+	config.Configuration.Auth.BlockList.File = ""
+
+	al := access.Service()
+
+	if err := al.StartService(); err != nil {
+		t.Fatal(err)
+	}
+
+	types.App.AddService(al)
+
+	t.Cleanup(func() {
+		_ = types.App.RemoveService(al.GetName())
+	})
+}
+
+// Authenticate a connection with a key, returning the kick sent to the client, or an empty string if it was let in
+func authenticate(t *testing.T, s *Server, key string) (*TCPConnection, string) {
+	t.Helper()
+
+	c, client := newTestConnection(t, s)
+
+	return c, authenticateWith(t, c, client, key, "")
+}
+
+// Authenticate a connection with a key, and a password if one is given.
+// Returns the kick sent to the client, or an empty string if it was let in
+func authenticateWith(t *testing.T, c *TCPConnection, client net.Conn, key string, password string) string {
+	t.Helper()
+
+	c.StartWriter()
+
+	done := make(chan struct{})
+	go func() {
+		c.Authenticate()
+		close(done)
+	}()
+
+	reader := types.NewFrameReader(client, types.MaxHeaderSize)
+
+	if packet := readFrame(t, reader, client); string(packet.Data) != "A" {
+		t.Fatalf("Expected the key to be requested, got %q", packet.Data)
+	}
+
+	packet := types.NewTcpPacket(key)
+	if _, err := client.Write(packet.Serialize()); err != nil {
+		t.Fatal(err)
+	}
+
+	if password != "" {
+		if packet := readFrame(t, reader, client); string(packet.Data) != "S" {
+			t.Fatalf("Expected the password to be requested, got %q", packet.Data)
+		}
+
+		packet := types.NewTcpPacket(password)
+		if _, err := client.Write(packet.Serialize()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	kicks := make(chan string, 1)
+	go func() {
+		if packet, err := reader.ReadFrame(); err == nil {
+			kicks <- string(packet.Data)
+		}
+	}()
+
+	waitClosed(t, done, "authentication")
+
+	if c.authorized {
+		return ""
+	}
+
+	select {
+	case kick := <-kicks:
+		return kick
+	case <-time.After(2 * time.Second):
+		t.Fatalf("%s: expected the player to be let in or kicked", key)
+		return ""
+	}
+}
+
+func TestServeStopsWhenNotAuthorized(t *testing.T) {
+	s := newTestServer(t)
+	c, client := newTestConnection(t, s)
+	c.StartWriter()
+
+	served := make(chan struct{})
+	go func() {
+		(&VC2{}).Serve(c, nil)
+		close(served)
+	}()
+
+	reader := types.NewFrameReader(client, types.MaxHeaderSize)
+
+	if packet := readFrame(t, reader, client); string(packet.Data) != "A" {
+		t.Fatalf("Expected the key to be requested, got %q", packet.Data)
+	}
+
+	// The client goes away instead of sending its key
+	_ = client.Close()
+
+	waitClosed(t, served, "Serve")
+
+	if state := c.GetState(); state != types.StateAuthenticate {
+		t.Errorf("Expected the connection to stop at authentication, got state %v", state)
+	}
+
+	if count := c.pm.Count(); count != 0 {
+		t.Errorf("Expected no players to be added, got %d", count)
+	}
+
+	if c.reservation != nil {
+		t.Errorf("Expected no slot to be reserved, got %d", *c.reservation)
+	}
+}
+
+func TestAuthenticateCheckOrder(t *testing.T) {
+	s := newTestServer(t)
+
+	auth := &config.Configuration.Auth
+	auth.AllowGuests = false
+	auth.AllowContentCreators = true
+	auth.AllowStaff = true
+	auth.AllowList.Enable = true
+	auth.AllowList.Players = []string{"blocked", "guest", "banned", "listed"}
+	auth.BlockList.Players = []string{"blocked"}
+	auth.Messages.Blocked = "Blocked"
+	auth.Messages.Guests = "No guests"
+	auth.Messages.NotAllowed = "Not allowed"
+	config.Configuration.General.Password = ""
+
+	newTestAPI(t, map[string]http.Player{
+		"blocked":  {Id: "blocked", Name: "blocked", Roles: "USER"},
+		"guest":    {Id: "guest", Name: "guest", Guest: true, Roles: "USER"},
+		"unlisted": {Id: "unlisted", Name: "unlisted", Roles: "USER"},
+		"banned":   {Id: "banned", Name: "banned", Roles: "USER"},
+		"listed":   {Id: "listed", Name: "listed", Roles: "USER"},
+	})
+	newTestAccessLists(t)
+
+	bs := newTestBans(t)
+
+	if _, err := bs.Add(bans.Ban{AccountId: "banned", Reason: "Cheating", Issuer: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key  string
+		kick string // The start of the kick sent to the client, or empty if the player is let in
+	}{
+		// The block list comes first, so the allow list does not let a blocked player in
+		{"blocked", "KBlocked"},
+		// The role rules come next, so the allow list does not let a guest in either
+		{"guest", "KNo guests"},
+		// Then the allow list itself
+		{"unlisted", "KNot allowed"},
+		// Bans are checked last, and apply to players on the allow list
+		{"banned", "KYou are banned"},
+		{"listed", ""},
+	}
+
+	for _, test := range tests {
+		c, kick := authenticate(t, s, test.key)
+
+		if test.kick == "" {
+			if kick != "" {
+				t.Errorf("%s: expected to be let in, got kicked with %q", test.key, kick)
+			}
+
+			if player := c.pm.GetPlayer(c.GetPlayer().PlayerId); player == nil || player.Account.Id != test.key {
+				t.Errorf("%s: expected the player to be added to the Player Manager", test.key)
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(kick, test.kick) {
+			t.Errorf("%s: expected a kick starting with %q, got %q", test.key, test.kick, kick)
+		}
+
+		if c.reservation != nil {
+			t.Errorf("%s: expected no slot to be reserved", test.key)
+		}
+	}
+}
+
+func TestAuthenticatePasswordLockout(t *testing.T) {
+	s := newTestServer(t)
+	s.Passwords = NewThrottle(2, time.Minute)
+
+	auth := &config.Configuration.Auth
+	auth.AllowGuests = true
+	auth.AllowList.Enable = false
+	config.Configuration.General.Password = "secret"
+
+	t.Cleanup(func() {
+		config.Configuration.General.Password = ""
+	})
+
+	newTestAPI(t, map[string]http.Player{
+		"player": {Id: "player", Name: "player", Roles: "USER"},
+	})
+
+	tests := []struct {
+		addr     string
+		password string // The password sent, or empty if the player is locked out before being asked for one
+		kick     string // The start of the kick sent to the client, or empty if the player is let in
+	}{
+		{"198.51.100.7:1000", "secret", ""},
+		{"198.51.100.7:1001", "wrong", "KIncorrect password"},
+		{"198.51.100.7:1002", "wrong", "KIncorrect password"},
+		// Reconnecting from a new port does not get around the lockout, even with the right password
+		{"198.51.100.7:1003", "", "KToo many incorrect passwords"},
+		// Other addresses are not locked out
+		{"198.51.100.8:1000", "secret", ""},
+	}
+
+	for _, test := range tests {
+		c, client := newTestConnectionFrom(t, s, test.addr)
+		kick := authenticateWith(t, c, client, "player", test.password)
+
+		if test.kick == "" {
+			if kick != "" {
+				t.Errorf("%s: expected to be let in, got kicked with %q", test.addr, kick)
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(kick, test.kick) {
+			t.Errorf("%s: expected a kick starting with %q, got %q", test.addr, test.kick, kick)
+		}
+
+		if c.authorized {
+			t.Errorf("%s: expected the connection not to be authorized", test.addr)
+		}
+	}
+
+	if locked, _ := s.Passwords.Locked("198.51.100.7:1004"); !locked {
+		t.Error("Expected the address to stay locked out")
+	}
+}
+
+func TestAuthenticateWithoutBans(t *testing.T) {
+	s := newTestServer(t)
+
+	config.Configuration.Auth.AllowGuests = true
+	config.Configuration.Auth.AllowList.Enable = false
+	config.Configuration.General.Password = ""
+
+	newTestAPI(t, map[string]http.Player{
+		"player": {Id: "player", Name: "player", Roles: "USER"},
+	})
+
+	// A ban service which has not started has no store, so bans cannot be checked
+	bs := bans.Service()
+	types.App.AddService(bs)
+
+	t.Cleanup(func() {
+		_ = types.App.RemoveService(bs.GetName())
+	})
+
+	c, kick := authenticate(t, s, "player")
+
+	if kick != "KThe server is experiencing an error - Please try again later" {
+		t.Errorf("Expected the player to be turned away, got %q", kick)
+	}
+
+	if c.reservation != nil {
+		t.Error("Expected no slot to be reserved")
+	}
+}
@@ -0,0 +1,87 @@
+package types
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+)
+
+// The prefix used by the BeamMP protocol to mark a zlib compressed payload
+const CompressionPrefix = "ABG:"
+
+// Payloads larger than this are compressed before being sent
+// Follows the threshold used in the official implementation
+const CompressionThreshold = 400
+
+// Returned when a compressed payload inflates to more than MaxHeaderSize
+var ErrDecompressedTooLarge = errors.New("decompressed payload exceeds maximum size")
+
+// IsCompressed reports whether the payload carries the compression prefix
+func IsCompressed(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(CompressionPrefix))
+}
+
+// Compress compresses a payload and adds the compression prefix
+func Compress(data []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString(CompressionPrefix)
+
+	writer := zlib.NewWriter(&buffer)
+
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Decompress inflates a prefixed payload.
+// The inflated size is capped at MaxHeaderSize to protect against decompression bombs.
+func Decompress(data []byte) ([]byte, error) {
+	if !IsCompressed(data) {
+		return data, nil
+	}
+
+	reader, err := zlib.NewReader(bytes.NewReader(data[len(CompressionPrefix):]))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	inflated, err := io.ReadAll(io.LimitReader(reader, MaxHeaderSize+1))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(inflated) > MaxHeaderSize {
+		return nil, ErrDecompressedTooLarge
+	}
+
+	return inflated, nil
+}
+
+// CompressPayload compresses a payload if it is larger than CompressionThreshold,
+// otherwise the payload is returned untouched
+func CompressPayload(data []byte) []byte {
+	if len(data) <= CompressionThreshold {
+		return data
+	}
+
+	compressed, err := Compress(data)
+
+	if err != nil {
+		return data
+	}
+
+	return compressed
+}
@@ -0,0 +1,93 @@
+package types
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"pos":[0,0,0]}`), 100)
+
+	compressed, err := Compress(data)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !IsCompressed(compressed) {
+		t.Fatal("Expected compressed payload to carry the ABG: prefix")
+	}
+
+	inflated, err := Decompress(compressed)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !bytes.Equal(inflated, data) {
+		t.Fatal("Inflated payload does not match the original")
+	}
+}
+
+func TestCompressPayloadThreshold(t *testing.T) {
+	small := bytes.Repeat([]byte("a"), CompressionThreshold)
+	large := bytes.Repeat([]byte("a"), CompressionThreshold+1)
+
+	if IsCompressed(CompressPayload(small)) {
+		t.Error("Expected payloads at the threshold to be left uncompressed")
+	}
+
+	if !IsCompressed(CompressPayload(large)) {
+		t.Error("Expected payloads over the threshold to be compressed")
+	}
+}
+
+func TestDecompressBomb(t *testing.T) {
+	var buffer bytes.Buffer
+	buffer.WriteString(CompressionPrefix)
+
+	writer := zlib.NewWriter(&buffer)
+
+	if _, err := io.CopyN(writer, zeroReader{}, MaxHeaderSize+1); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err := Decompress(buffer.Bytes())
+
+	if !errors.Is(err, ErrDecompressedTooLarge) {
+		t.Fatalf("Expected ErrDecompressedTooLarge, got %v", err)
+	}
+}
+
+func TestReadFrameInflates(t *testing.T) {
+	data := bytes.Repeat([]byte("Os:0:{}"), 100)
+	packet := NewTcpPacket(data)
+
+	reader := NewFrameReader(bytes.NewReader(packet.Serialize()), MaxHeaderSize)
+
+	received, err := reader.ReadFrame()
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !bytes.Equal(received.Data, data) {
+		t.Fatal("Expected frame to be inflated transparently")
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
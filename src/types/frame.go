@@ -0,0 +1,160 @@
+package types
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The size of the read buffer held by each FrameReader
+const FrameReaderBufferSize = 64 * Kilobyte
+
+// The amount the data buffer of a frame is grown by at a time.
+// Growing in steps means a client cannot make the server allocate a full frame
+// simply by sending a large header and then never sending the data.
+const FrameChunkSize = 64 * Kilobyte
+
+// Returned (wrapped in a FrameError) when a client sends a negative frame header
+var ErrNegativeFrame = errors.New("negative frame length")
+
+// Returned (wrapped in a FrameError) when a client sends a frame header larger than the reader allows
+var ErrFrameTooLarge = errors.New("frame length exceeds limit")
+
+// FrameError is returned when a frame header violates the protocol.
+// Connections that receive one of these should be closed, as the stream can no longer be trusted.
+type FrameError struct {
+	Header int32 // The header value sent by the client
+	Limit  int32 // The maximum frame size accepted by the reader
+	Err    error // The reason the frame was rejected (ErrNegativeFrame or ErrFrameTooLarge)
+}
+
+func (e *FrameError) Error() string {
+	return fmt.Sprintf("%s (header: %d, limit: %d)", e.Err.Error(), e.Header, e.Limit)
+}
+
+func (e *FrameError) Unwrap() error {
+	return e.Err
+}
+
+// FrameReader reads complete TCP frames from a stream.
+//
+// Frames that are split across several reads (or interrupted by a read deadline) are
+// resumed on the next call to ReadFrame, so a timeout never desynchronises the stream.
+type FrameReader struct {
+	reader     *bufio.Reader // Buffered reader around the connection
+	limit      int32         // Maximum size of a single frame
+	header     [4]byte       // The header of the frame currently being read
+	headerRead int           // How many bytes of the header have been read
+	length     int32         // The length of the frame currently being read (valid once the header is complete)
+	data       []byte        // The data of the frame currently being read
+}
+
+// NewFrameReader creates a new FrameReader which rejects frames larger than limit
+func NewFrameReader(r io.Reader, limit int32) *FrameReader {
+	if limit <= 0 || limit > MaxHeaderSize {
+		limit = MaxHeaderSize
+	}
+
+	return &FrameReader{
+		reader: bufio.NewReaderSize(r, FrameReaderBufferSize),
+		limit:  limit,
+	}
+}
+
+// ReadByte reads a single unframed byte from the stream (used for the identification byte).
+// It must not be called whilst a frame is partially read.
+func (f *FrameReader) ReadByte() (byte, error) {
+	if f.headerRead != 0 {
+		return 0, fmt.Errorf("cannot read a raw byte in the middle of a frame")
+	}
+
+	return f.reader.ReadByte()
+}
+
+// ReadFrame reads the next complete frame from the stream, decompressing it if required.
+// If an error is returned before the frame is complete, the progress is kept and the
+// next call continues where this one stopped. FrameErrors are not recoverable.
+func (f *FrameReader) ReadFrame() (TcpPacket, error) {
+	for f.headerRead < len(f.header) {
+		n, err := f.reader.Read(f.header[f.headerRead:])
+		f.headerRead += n
+
+		if f.headerRead == len(f.header) {
+			break
+		}
+
+		if err != nil {
+			return NewEmptyTcpPacket(), err
+		}
+	}
+
+	if f.data == nil {
+		length := int32(binary.LittleEndian.Uint32(f.header[:]))
+
+		if length < 0 {
+			f.reset()
+			return NewEmptyTcpPacket(), &FrameError{Header: length, Limit: f.limit, Err: ErrNegativeFrame}
+		}
+
+		if length > f.limit {
+			f.reset()
+			return NewEmptyTcpPacket(), &FrameError{Header: length, Limit: f.limit, Err: ErrFrameTooLarge}
+		}
+
+		f.length = length
+		f.data = make([]byte, 0, minInt(int(length), FrameChunkSize))
+	}
+
+	for len(f.data) < int(f.length) {
+		if len(f.data) == cap(f.data) {
+			grown := make([]byte, len(f.data), minInt(int(f.length), cap(f.data)+FrameChunkSize))
+			copy(grown, f.data)
+			f.data = grown
+		}
+
+		n, err := f.reader.Read(f.data[len(f.data):cap(f.data)])
+		f.data = f.data[:len(f.data)+n]
+
+		if len(f.data) == int(f.length) {
+			break
+		}
+
+		if err != nil {
+			return NewEmptyTcpPacket(), err
+		}
+	}
+
+	packet := TcpPacket{
+		Header: f.length,
+		Data:   f.data,
+	}
+
+	f.reset()
+
+	if err := packet.Inflate(); err != nil {
+		return NewEmptyTcpPacket(), err
+	}
+
+	return packet, nil
+}
+
+// Buffered returns the number of bytes that have been received but not yet consumed
+func (f *FrameReader) Buffered() int {
+	return f.reader.Buffered()
+}
+
+// reset clears the state of the current frame
+func (f *FrameReader) reset() {
+	f.headerRead = 0
+	f.length = 0
+	f.data = nil
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
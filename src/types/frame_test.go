@@ -0,0 +1,141 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+var errTimeout = errors.New("i/o timeout")
+
+// A reader which returns its chunks one at a time, optionally failing between chunks
+type chunkedReader struct {
+	chunks [][]byte
+	fail   bool
+	failed bool
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+
+	if r.fail && !r.failed {
+		r.failed = true
+		return 0, errTimeout
+	}
+
+	r.failed = false
+	n := copy(p, r.chunks[0])
+	r.chunks[0] = r.chunks[0][n:]
+
+	if len(r.chunks[0]) == 0 {
+		r.chunks = r.chunks[1:]
+	}
+
+	return n, nil
+}
+
+func frame(header int32, data []byte) []byte {
+	payload := make([]byte, 4)
+	binary.LittleEndian.PutUint32(payload, uint32(header))
+	return append(payload, data...)
+}
+
+func TestReadFrameSplit(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 3*FrameChunkSize+7)
+	raw := frame(int32(len(data)), data)
+
+	reader := NewFrameReader(&chunkedReader{
+		chunks: [][]byte{raw[:2], raw[2:9], raw[9:1500], raw[1500:]},
+	}, MaxHeaderSize)
+
+	packet, err := reader.ReadFrame()
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !bytes.Equal(packet.Data, data) {
+		t.Fatalf("Expected %d bytes of data, got %d", len(data), len(packet.Data))
+	}
+}
+
+func TestReadFrameResumesAfterError(t *testing.T) {
+	raw := append(frame(5, []byte("Hello")), frame(2, []byte("SR"))...)
+
+	reader := NewFrameReader(&chunkedReader{
+		chunks: [][]byte{raw[:3], raw[3:6], raw[6:]},
+		fail:   true,
+	}, MaxHeaderSize)
+
+	received := []string{}
+
+	for attempts := 0; len(received) < 2 && attempts < 20; attempts++ {
+		packet, err := reader.ReadFrame()
+
+		if err != nil {
+			if err != errTimeout {
+				t.Fatal(err.Error())
+			}
+			continue
+		}
+
+		received = append(received, packet.String())
+	}
+
+	if len(received) != 2 || received[0] != "Hello" || received[1] != "SR" {
+		t.Fatalf("Expected [Hello SR], got %v", received)
+	}
+}
+
+func TestReadFrameRejectsInvalidHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		header int32
+		limit  int32
+		want   error
+	}{
+		{"negative", -1, MaxHeaderSize, ErrNegativeFrame},
+		{"over limit", 1025, 1024, ErrFrameTooLarge},
+		{"over max header size", MaxHeaderSize + 1, MaxHeaderSize, ErrFrameTooLarge},
+	}
+
+	for _, test := range tests {
+		reader := NewFrameReader(bytes.NewReader(frame(test.header, nil)), test.limit)
+
+		_, err := reader.ReadFrame()
+
+		var frameErr *FrameError
+		if !errors.As(err, &frameErr) {
+			t.Errorf("%s: expected a FrameError, got %v", test.name, err)
+			continue
+		}
+
+		if !errors.Is(err, test.want) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.want, frameErr.Err)
+		}
+	}
+}
+
+func TestReadTcpPacket(t *testing.T) {
+	packet, err := ReadTcpPacket(&chunkedReader{
+		chunks: [][]byte{frame(6, nil), []byte("VC"), []byte("2.0.0")[:4]},
+	})
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if packet.String() != "VC2.0." {
+		t.Errorf("Expected VC2.0., got %s", packet.String())
+	}
+
+	_, err = ReadTcpPacket(bytes.NewReader(frame(-5, nil)))
+
+	if !errors.Is(err, ErrNegativeFrame) {
+		t.Errorf("Expected ErrNegativeFrame, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package types
+
+import "time"
+
+// Latency tracks the round trip time of a player, smoothed over the samples received
+type Latency struct {
+	RTT       time.Duration // The smoothed round trip time
+	Jitter    time.Duration // The smoothed variation between consecutive round trip times
+	Samples   int           // The number of samples received
+	UpdatedAt time.Time     // When the last sample was received
+	HighSince time.Time     // When the round trip time went above the ping limit (zero while it is below)
+
+	last time.Duration // The previous sample, used to calculate the jitter
+}
+
+// Sample records a round trip time measurement.
+// The round trip time is smoothed as in TCP (RFC 6298), and the jitter as in RTP (RFC 3550).
+func (l *Latency) Sample(rtt time.Duration, now time.Time) {
+	if l.Samples == 0 {
+		l.RTT = rtt
+	} else {
+		l.RTT += (rtt - l.RTT) / 8
+
+		delta := rtt - l.last
+		if delta < 0 {
+			delta = -delta
+		}
+
+		l.Jitter += (delta - l.Jitter) / 16
+	}
+
+	l.last = rtt
+	l.Samples++
+	l.UpdatedAt = now
+}
+
+// Exceeds tracks whether the round trip time is above a limit, returning how long it has been above it
+func (l *Latency) Exceeds(limit time.Duration, now time.Time) time.Duration {
+	if l.RTT <= limit {
+		l.HighSince = time.Time{}
+		return 0
+	}
+
+	if l.HighSince.IsZero() {
+		l.HighSince = now
+	}
+
+	return now.Sub(l.HighSince)
+}
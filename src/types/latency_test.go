@@ -0,0 +1,50 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencySample(t *testing.T) {
+	var l Latency
+	now := time.Unix(1000, 0)
+
+	l.Sample(80*time.Millisecond, now)
+
+	if l.RTT != 80*time.Millisecond || l.Jitter != 0 {
+		t.Fatalf("Expected the first sample to set the RTT, got %s (jitter %s)", l.RTT, l.Jitter)
+	}
+
+	l.Sample(160*time.Millisecond, now)
+
+	if l.RTT != 90*time.Millisecond {
+		t.Errorf("Expected an RTT of 90ms, got %s", l.RTT)
+	}
+
+	if l.Jitter != 5*time.Millisecond {
+		t.Errorf("Expected a jitter of 5ms, got %s", l.Jitter)
+	}
+
+	if l.Samples != 2 {
+		t.Errorf("Expected 2 samples, got %d", l.Samples)
+	}
+}
+
+func TestLatencyExceeds(t *testing.T) {
+	l := Latency{RTT: 500 * time.Millisecond}
+	now := time.Unix(1000, 0)
+
+	if d := l.Exceeds(300*time.Millisecond, now); d != 0 {
+		t.Errorf("Expected the limit to have just been exceeded, got %s", d)
+	}
+
+	if d := l.Exceeds(300*time.Millisecond, now.Add(10*time.Second)); d != 10*time.Second {
+		t.Errorf("Expected the limit to have been exceeded for 10s, got %s", d)
+	}
+
+	l.RTT = 100 * time.Millisecond
+
+	if d := l.Exceeds(300*time.Millisecond, now.Add(20*time.Second)); d != 0 || !l.HighSince.IsZero() {
+		t.Errorf("Expected the tracking to reset, got %s", d)
+	}
+}
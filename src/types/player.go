@@ -10,15 +10,26 @@ type Account struct {
 	Id          string   // The player's ID internally to BeamMP
 	Guest       bool     // Whether the player is a guest account or not
 	Identifiers []string // The player's identifiers
-	Roles       []string // The roles of the player
+	Roles       []Role   // The roles of the player
 	UserId      int64    // The user ID of the player on the forum
 }
 
+// Key identifies the account in records kept about it, using the name for guest accounts which have no ID
+func (a *Account) Key() string {
+	if a.Id != "" {
+		return a.Id
+	}
+
+	return "guest:" + a.Name
+}
+
 // A structure representing a player in the game itself
 type Player struct {
 	Status      PlayerStatus            // The status of the player
 	DisplayName string                  // The name of the player (can be changed by the client through plugins)
 	Address     net.Addr                // The IP address of the player
+	UDPAddress  *net.UDPAddr            // The address the player sends UDP traffic from, once it is known
+	Latency     Latency                 // The round trip time of the player
 	PlayerId    int                     // The ID of the player within the game's ID system
 	Vehicles    []*Vehicle              // The vehicles actively owned by the player in the current session
 	Account     *Account                // The account information for the player from the BeamMP API
@@ -0,0 +1,96 @@
+package types
+
+import "strings"
+
+// A Role given to an account by BeamMP, as sent by the authentication API (eg. USER)
+type Role string
+
+const (
+	RoleUser           Role = "USER"    // A regular player
+	RoleEarlyAccess    Role = "EA"      // A player with early access to new releases
+	RoleContentCreator Role = "YT"      // A content creator
+	RoleSupport        Role = "SUPPORT" // A member of the BeamMP support team
+	RoleModerator      Role = "MOD"     // A BeamMP forum moderator
+	RoleAdmin          Role = "ADM"     // A BeamMP administrator
+	RoleStaff          Role = "STAFF"   // A member of the BeamMP team
+	RoleDeveloper      Role = "MDEV"    // A BeamMP developer
+	RoleGameDeveloper  Role = "GDEV"    // A BeamNG developer
+	RoleNetDeveloper   Role = "NGDEV"   // A BeamNG developer working on networking
+)
+
+// Roles which belong to the BeamMP or BeamNG teams
+var staffRoles = map[Role]bool{
+	RoleSupport:       true,
+	RoleModerator:     true,
+	RoleAdmin:         true,
+	RoleStaff:         true,
+	RoleDeveloper:     true,
+	RoleGameDeveloper: true,
+	RoleNetDeveloper:  true,
+}
+
+// ParseRoles reads the roles sent by the authentication API, which may be separated by commas or spaces.
+// Roles which are not known are kept, so that they can still be relayed to other clients.
+func ParseRoles(raw string) []Role {
+	roles := []Role{}
+
+	for _, field := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ' ' }) {
+		roles = append(roles, Role(strings.ToUpper(field)))
+	}
+
+	return roles
+}
+
+// FormatRoles joins roles back into the form sent by the authentication API
+func FormatRoles(roles []Role) string {
+	fields := make([]string, len(roles))
+
+	for i, role := range roles {
+		fields[i] = string(role)
+	}
+
+	return strings.Join(fields, ",")
+}
+
+// IsStaff reports whether the role belongs to the BeamMP or BeamNG teams
+func (r Role) IsStaff() bool {
+	return staffRoles[r]
+}
+
+// IsContentCreator reports whether the role is given to content creators
+func (r Role) IsContentCreator() bool {
+	return r == RoleContentCreator
+}
+
+// HasRole reports whether the account has a role
+func (a *Account) HasRole(role Role) bool {
+	for _, r := range a.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsStaff reports whether the account belongs to a member of the BeamMP or BeamNG teams
+func (a *Account) IsStaff() bool {
+	for _, r := range a.Roles {
+		if r.IsStaff() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsContentCreator reports whether the account belongs to a content creator
+func (a *Account) IsContentCreator() bool {
+	for _, r := range a.Roles {
+		if r.IsContentCreator() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,39 @@
+package types
+
+import "testing"
+
+func TestParseRoles(t *testing.T) {
+	roles := ParseRoles("yt, STAFF custom")
+
+	if len(roles) != 3 || roles[0] != RoleContentCreator || roles[1] != RoleStaff || roles[2] != Role("CUSTOM") {
+		t.Fatalf("Unexpected roles: %v", roles)
+	}
+
+	if formatted := FormatRoles(roles); formatted != "YT,STAFF,CUSTOM" {
+		t.Errorf("Expected the roles to be formatted as YT,STAFF,CUSTOM, got %s", formatted)
+	}
+
+	if roles := ParseRoles(""); len(roles) != 0 {
+		t.Errorf("Expected no roles, got %v", roles)
+	}
+}
+
+func TestAccountRoles(t *testing.T) {
+	account := &Account{Roles: ParseRoles("USER")}
+
+	if account.IsStaff() || account.IsContentCreator() || !account.HasRole(RoleUser) {
+		t.Errorf("Expected a regular user, got %v", account.Roles)
+	}
+
+	account.Roles = ParseRoles("MDEV")
+
+	if !account.IsStaff() {
+		t.Error("Expected developers to be staff")
+	}
+
+	account.Roles = ParseRoles("YT")
+
+	if !account.IsContentCreator() {
+		t.Error("Expected a content creator")
+	}
+}
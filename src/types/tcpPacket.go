@@ -2,9 +2,8 @@ package types
 
 import (
 	"encoding/binary"
-	"fmt"
+	"io"
 	"math"
-	"net"
 )
 
 // TcpPacket is a wrapper around a byte array that contains a header and Data
@@ -48,42 +47,47 @@ func NewTcpPacket(Data any) TcpPacket {
 	}
 }
 
-func (p *TcpPacket) ReadHeader(c net.Conn) (int32, error) {
+// ReadHeader reads and validates the 4 byte header of a frame.
+// Negative headers and headers larger than MaxHeaderSize are rejected with a FrameError.
+func (p *TcpPacket) ReadHeader(c io.Reader) (int32, error) {
 	header := make([]byte, 4)
-	bytesRead, err := c.Read(header)
+	_, err := io.ReadFull(c, header)
 	if err != nil {
 		return 0, err
 	}
 
-	if bytesRead != 4 {
-		return 0, fmt.Errorf("invalid header length")
+	FrameLength := int32(binary.LittleEndian.Uint32(header))
+
+	if FrameLength < 0 {
+		return 0, &FrameError{Header: FrameLength, Limit: MaxHeaderSize, Err: ErrNegativeFrame}
 	}
 
-	FrameLength := int32(header[3])<<24 | int32(header[2])<<16 | int32(header[1])<<8 | int32(header[0])
+	if FrameLength > MaxHeaderSize {
+		return 0, &FrameError{Header: FrameLength, Limit: MaxHeaderSize, Err: ErrFrameTooLarge}
+	}
 
 	p.Header = FrameLength
 
 	return FrameLength, nil
 }
 
-func (p *TcpPacket) ReadData(c net.Conn) ([]byte, error) {
+// ReadData reads the data of a frame, based on the previously read header
+func (p *TcpPacket) ReadData(c io.Reader) ([]byte, error) {
 	dataLength := p.Header
 	Data := make([]byte, dataLength)
-	bytesRead, err := c.Read(Data)
+	_, err := io.ReadFull(c, Data)
 	if err != nil {
 		return nil, err
 	}
 
-	if bytesRead != int(dataLength) {
-		return nil, fmt.Errorf("invalid Data length")
-	}
-
 	p.Data = Data
 
 	return Data, nil
 }
 
-func ReadTcpPacket(c net.Conn) (TcpPacket, error) {
+// ReadTcpPacket reads a single frame directly from an unbuffered stream.
+// Long lived connections should use a FrameReader instead, which survives read deadlines.
+func ReadTcpPacket(c io.Reader) (TcpPacket, error) {
 	var TcpPacket TcpPacket
 	_, err := TcpPacket.ReadHeader(c)
 
@@ -97,7 +101,9 @@ func ReadTcpPacket(c net.Conn) (TcpPacket, error) {
 		return TcpPacket, err
 	}
 
-	return TcpPacket, nil
+	err = TcpPacket.Inflate()
+
+	return TcpPacket, err
 }
 
 func (TcpPacket) FromString(s string) *TcpPacket {
@@ -163,15 +169,36 @@ func (p *TcpPacket) WriteFloat64(f float64) {
 	p.Header = int32(len(p.Data))
 }
 
+// Serialize the packet into a frame ready to be written to the connection.
+// Data larger than CompressionThreshold is compressed with the ABG: prefix.
 func (p *TcpPacket) Serialize() []byte {
-	payload := make([]byte, p.Header+4)
-	binary.LittleEndian.PutUint32(payload, uint32(p.Header))
-	payload = payload[:4]
-	payload = append(payload, p.Data...)
+	data := CompressPayload(p.Data)
+
+	payload := make([]byte, 4, len(data)+4)
+	binary.LittleEndian.PutUint32(payload, uint32(len(data)))
+	payload = append(payload, data...)
 
 	return payload
 }
 
+// Inflate decompresses the packet data in place if it carries the ABG: prefix
+func (p *TcpPacket) Inflate() error {
+	if !IsCompressed(p.Data) {
+		return nil
+	}
+
+	data, err := Decompress(p.Data)
+
+	if err != nil {
+		return err
+	}
+
+	p.Data = data
+	p.Header = int32(len(data))
+
+	return nil
+}
+
 func (p *TcpPacket) Code(position int) rune {
 	return rune(p.Data[position])
 }
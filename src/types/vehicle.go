@@ -1,7 +1,24 @@
 package types
 
-type Vehicle struct{}
+import (
+	"fmt"
+	"time"
+)
+
+// A structure representing a vehicle spawned by a player
+type Vehicle struct {
+	Id        int       // The ID of the vehicle, unique to its owner
+	OwnerId   int       // The player ID of the owner
+	Config    string    // The vehicle configuration (JSON), as last spawned or edited
+	State     string    // The last known state of the vehicle (JSON), as last reset
+	UpdatedAt time.Time // When the vehicle was last changed
+
+	Position [3]float64 // The last known position of the vehicle
+	Rotation [4]float64 // The last known rotation of the vehicle, as a quaternion
+	Velocity [3]float64 // The last known velocity of the vehicle
+	MovedAt  time.Time  // When the transform was last received (zero until the first one arrives)
+}
 
 func (v *Vehicle) String() string {
-	return "Vehicle"
+	return fmt.Sprintf("%d-%d", v.OwnerId, v.Id)
 }
@@ -0,0 +1,155 @@
+package udp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/tcp"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// How often the server pings each player to measure their round trip time
+const PingInterval = 2 * time.Second
+
+// The size of the random token carried by server pings, which the client echoes back
+const PingTokenSize = 8
+
+// A ping sent by the server which has not been answered yet
+type pendingPing struct {
+	token [PingTokenSize]byte // Identifies the echo of the ping
+	sent  time.Time           // When the ping was sent
+}
+
+// Pinger tracks the pings sent to each player, so that round trip times are measured by the server
+type Pinger struct {
+	mutex   sync.Mutex
+	pending map[*tcp.TCPConnection]pendingPing // The unanswered ping of each player
+}
+
+// Create a new pinger
+func NewPinger() *Pinger {
+	return &Pinger{
+		pending: make(map[*tcp.TCPConnection]pendingPing),
+	}
+}
+
+// Ping creates a ping for a player, replacing any earlier ping they have not answered
+func (p *Pinger) Ping(session *tcp.TCPConnection, now time.Time) ([]byte, error) {
+	ping := pendingPing{sent: now}
+
+	if _, err := rand.Read(ping.token[:]); err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	p.pending[session] = ping
+	p.mutex.Unlock()
+
+	return append([]byte{'p'}, ping.token[:]...), nil
+}
+
+// Answer matches the echo of a ping, returning the round trip time since the ping was sent.
+// Each ping may only be answered once, and echoes of unknown pings are ignored.
+func (p *Pinger) Answer(session *tcp.TCPConnection, data []byte, now time.Time) (time.Duration, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	ping, ok := p.pending[session]
+
+	if !ok || len(data) != 1+PingTokenSize || !bytes.Equal(data[1:], ping.token[:]) {
+		return 0, false
+	}
+
+	delete(p.pending, session)
+
+	return now.Sub(ping.sent), true
+}
+
+// Forget a player who has left the server
+func (p *Pinger) Forget(session *tcp.TCPConnection) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	delete(p.pending, session)
+}
+
+// PingLoop pings every playing player at a regular interval, until the server stops
+func (s *Server) PingLoop() {
+	ticker := time.NewTicker(PingInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if *s.Status != types.StatusHealthy {
+			return
+		}
+
+		for _, session := range s.tcp.Connections.Snapshot() {
+			if session.GetState() != types.StatePlaying || session.GetPlayer().UDPAddress == nil {
+				continue
+			}
+
+			data, err := s.pinger.Ping(session, now)
+
+			if err != nil {
+				s.Error("Error creating ping - Additional output below")
+				s.Error(err.Error())
+				return
+			}
+
+			if err = s.Send(session, data); err != nil {
+				s.Debugf("Unable to ping %s - %s", session.Address, err.Error())
+			}
+		}
+	}
+}
+
+// HandlePing answers a ping from the client, which it uses to measure its own round trip time.
+// A ping carrying a token is the echo of a server ping, and records the round trip time measured by the server.
+func (s *Server) HandlePing(session *tcp.TCPConnection, packet *Packet) {
+	if len(packet.Data) > 1 {
+		if rtt, ok := s.pinger.Answer(session, packet.Data, time.Now()); ok {
+			s.RecordLatency(session, rtt)
+		} else {
+			s.Debugf("Ignoring ping reply from player %d - It does not match the last ping sent", packet.PlayerId)
+		}
+		return
+	}
+
+	if err := s.Send(session, []byte("p")); err != nil {
+		s.Debugf("Unable to answer ping from player %d - %s", packet.PlayerId, err.Error())
+	}
+}
+
+// RecordLatency adds a round trip time measured by the server to its player,
+// disconnecting the player if their ping has stayed above the configured limit for too long
+func (s *Server) RecordLatency(session *tcp.TCPConnection, rtt time.Duration) {
+	settings := config.Configuration.Auth.Ping
+	limit := time.Duration(settings.MaxPing) * time.Millisecond
+	now := time.Now()
+
+	var high time.Duration
+
+	session.UpdatePlayer(func(p *types.Player) {
+		p.Latency.Sample(rtt, now)
+
+		if !settings.Enable {
+			return
+		}
+
+		high = p.Latency.Exceeds(limit, now)
+
+		// Start again, so that the player is only kicked once
+		if high > settings.DurationTime {
+			p.Latency.HighSince = time.Time{}
+		}
+	})
+
+	if settings.Enable && high > settings.DurationTime {
+		session.Warnf("Ping has been above %dms for %s", settings.MaxPing, high.Round(time.Second))
+		session.Disconnect(fmt.Sprintf("Your ping was above %dms for too long", settings.MaxPing))
+	}
+}
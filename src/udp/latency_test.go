@@ -0,0 +1,65 @@
+package udp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/tcp"
+)
+
+func TestPingerMeasuresEcho(t *testing.T) {
+	p := NewPinger()
+	session := &tcp.TCPConnection{}
+	now := time.Unix(1000, 0)
+
+	ping, err := p.Ping(session, now)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ping) != 1+PingTokenSize || ping[0] != 'p' {
+		t.Fatalf("Expected a ping carrying a token, got %q", ping)
+	}
+
+	// A reply which does not carry the token of the last ping is not trusted
+	forged := append([]byte{}, ping...)
+	forged[1] ^= 0xFF
+
+	if _, ok := p.Answer(session, forged, now.Add(time.Millisecond)); ok {
+		t.Error("Expected a reply with the wrong token to be ignored")
+	}
+
+	if _, ok := p.Answer(session, []byte("p"), now.Add(time.Millisecond)); ok {
+		t.Error("Expected a client ping not to answer the server ping")
+	}
+
+	rtt, ok := p.Answer(session, ping, now.Add(42*time.Millisecond))
+
+	if !ok || rtt != 42*time.Millisecond {
+		t.Errorf("Expected a round trip time of 42ms, got %s (%t)", rtt, ok)
+	}
+
+	if _, ok := p.Answer(session, ping, now.Add(time.Second)); ok {
+		t.Error("Expected a ping to only be answered once")
+	}
+}
+
+func TestPingerReplacesUnansweredPing(t *testing.T) {
+	p := NewPinger()
+	session := &tcp.TCPConnection{}
+	now := time.Unix(1000, 0)
+
+	first, _ := p.Ping(session, now)
+	second, _ := p.Ping(session, now.Add(PingInterval))
+
+	if _, ok := p.Answer(session, first, now.Add(PingInterval+time.Millisecond)); ok {
+		t.Error("Expected the echo of a replaced ping to be ignored")
+	}
+
+	p.Forget(session)
+
+	if _, ok := p.Answer(session, second, now.Add(PingInterval+time.Millisecond)); ok {
+		t.Error("Expected the pings of a forgotten player to be ignored")
+	}
+}
@@ -1,27 +1,120 @@
 package udp
 
-import "net"
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// The largest datagram which can be received, so that no datagram is ever truncated
+const MaxDatagramSize = 65535
+
+// Returned when a datagram is too short to contain a player ID
+var ErrShortPacket = errors.New("udp packet is too short")
+
+// Returned when a datagram was larger than the receive buffer
+var ErrTruncatedPacket = errors.New("udp packet was truncated")
+
+// Receive buffers are reused between packets, as every datagram would otherwise need a new one
+var packetPool = sync.Pool{
+	New: func() interface{} {
+		return &Packet{buf: make([]byte, MaxDatagramSize)}
+	},
+}
 
 // A UDP packet
+//
+//	<player ID + 1>:<data>
 type Packet struct {
-	Header int32
-	Data   []byte
-	Source net.Addr
+	PlayerId int            // The player ID claimed by the sender
+	Data     []byte         // The payload of the packet, inflated if the client compressed it
+	Source   netip.AddrPort // The address the packet was sent from
+
+	buf       []byte // The receive buffer
+	size      int    // The number of bytes received into the buffer
+	truncated bool   // Whether the datagram was larger than the receive buffer
+}
+
+// AcquirePacket takes a packet with an empty receive buffer from the pool
+func AcquirePacket() *Packet {
+	return packetPool.Get().(*Packet)
 }
 
+// Release returns a packet to the pool.
+// The packet data may point into the receive buffer, so it must not be used afterwards.
+func (p *Packet) Release() {
+	p.PlayerId, p.Data, p.Source, p.size, p.truncated = 0, nil, netip.AddrPort{}, 0, false
+	packetPool.Put(p)
+}
+
+// ReadPacketFromUDP reads a single datagram into a pooled packet.
+// The packet should be released once it has been handled, and is released here if it cannot be decoded.
 func ReadPacketFromUDP(connection *net.UDPConn) (*Packet, error) {
-	p := &Packet{}
+	p := AcquirePacket()
 
-	buf := make([]byte, 1024)
-	_, addr, err := connection.ReadFromUDP(buf)
+	n, addr, err := connection.ReadFromUDPAddrPort(p.buf)
 
 	if err != nil {
+		p.Release()
 		return nil, err
 	}
 
-	p.Header = int32(buf[0])<<24 | int32(buf[1])<<16 | int32(buf[2])<<8 | int32(buf[3])
-	p.Data = buf[4:]
-	p.Source = addr
+	p.received(n, addr, false)
+
+	if err = p.Decode(); err != nil {
+		p.Release()
+		return nil, err
+	}
 
 	return p, nil
 }
+
+// Record a datagram which was received into the buffer
+func (p *Packet) received(n int, source netip.AddrPort, truncated bool) {
+	p.size = n
+	p.truncated = truncated
+	p.Source = netip.AddrPortFrom(source.Addr().Unmap(), source.Port())
+}
+
+// Decode the player ID and payload of the datagram in the receive buffer.
+// The payload is not copied unless it was compressed, so it shares the lifetime of the packet.
+func (p *Packet) Decode() error {
+	if p.truncated {
+		return ErrTruncatedPacket
+	}
+
+	if p.size < 2 {
+		return ErrShortPacket
+	}
+
+	// The client sends its player ID offset by one, followed by a separator
+	p.PlayerId = int(p.buf[0]) - 1
+
+	// Inflate the payload if the client compressed it
+	data, err := types.Decompress(p.buf[2:p.size])
+
+	if err != nil {
+		return err
+	}
+
+	p.Data = data
+
+	return nil
+}
+
+// Code returns the message code of the packet (the first byte of its data)
+func (p *Packet) Code() byte {
+	if len(p.Data) == 0 {
+		return 0
+	}
+
+	return p.Data[0]
+}
+
+// Serialize the packet data ready to be sent, compressing it if it is large enough
+func (p *Packet) Serialize() []byte {
+	return types.CompressPayload(p.Data)
+}
@@ -0,0 +1,168 @@
+package udp
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/protocol"
+	"github.com/altriusrs/netbeams/src/tcp"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// Relay tracks when each vehicle was last sent to each player, so that far away vehicles can be sent less often
+type Relay struct {
+	mutex sync.Mutex
+	sent  map[*tcp.TCPConnection]map[string]time.Time // When each vehicle was last sent, keyed by receiver and vehicle
+}
+
+// Create a new relay
+func NewRelay() *Relay {
+	return &Relay{
+		sent: make(map[*tcp.TCPConnection]map[string]time.Time),
+	}
+}
+
+// Due reports whether a vehicle update should be sent to a receiver, recording it as sent if so
+func (r *Relay) Due(receiver *tcp.TCPConnection, vehicle string, interval time.Duration, now time.Time) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	vehicles, ok := r.sent[receiver]
+
+	if !ok {
+		vehicles = make(map[string]time.Time)
+		r.sent[receiver] = vehicles
+	}
+
+	if last, ok := vehicles[vehicle]; ok && now.Sub(last) < interval {
+		return false
+	}
+
+	vehicles[vehicle] = now
+	return true
+}
+
+// Forget a receiver which has left the server
+func (r *Relay) Forget(receiver *tcp.TCPConnection) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.sent, receiver)
+}
+
+// RelayInterval returns the minimum time between updates for a vehicle at a distance from a player
+func RelayInterval(distance float64) time.Duration {
+	settings := config.Configuration.NetBeams
+
+	switch {
+	case distance < float64(settings.RelayNearDistance):
+		return 0
+	case distance < float64(settings.RelayFarDistance):
+		return settings.RelayMidIntervalTime
+	default:
+		return settings.RelayFarIntervalTime
+	}
+}
+
+// Distance returns the distance between two positions
+func Distance(a [3]float64, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+// Get the distance from a position to the closest vehicle of a player.
+// Players without a positioned vehicle (such as spectators) are treated as being next to it.
+func nearestVehicle(player *types.Player, position [3]float64) float64 {
+	nearest := 0.0
+	found := false
+
+	for _, v := range player.Vehicles {
+		if v.MovedAt.IsZero() {
+			continue
+		}
+
+		if d := Distance(v.Position, position); !found || d < nearest {
+			nearest, found = d, true
+		}
+	}
+
+	return nearest
+}
+
+// HandlePosition records the transform of a vehicle and relays it to the other players
+func (s *Server) HandlePosition(session *tcp.TCPConnection, packet *Packet) {
+	if len(packet.Data) < 2 || packet.Data[1] != 'p' {
+		s.Debugf("Ignoring UDP message %q from player %d", packet.Data[:minInt(len(packet.Data), 2)], packet.PlayerId)
+		return
+	}
+
+	var position protocol.VehiclePosition
+
+	if err := position.Decode(packet.Data); err != nil {
+		s.Debugf("Dropped position from player %d - %s", packet.PlayerId, err.Error())
+		return
+	}
+
+	now := time.Now()
+	found := false
+
+	session.UpdatePlayer(func(p *types.Player) {
+		if p.PlayerId != position.PlayerId {
+			return
+		}
+
+		for _, v := range p.Vehicles {
+			if v.Id == position.VehicleId {
+				v.Position, v.Rotation, v.Velocity, v.MovedAt = position.Position, position.Rotation, position.Velocity, now
+				found = true
+				return
+			}
+		}
+	})
+
+	if !found {
+		s.Debugf("Dropped position for vehicle %s - It does not belong to player %d", protocol.FormatVehicleId(position.PlayerId, position.VehicleId), packet.PlayerId)
+		return
+	}
+
+	vehicle := protocol.FormatVehicleId(position.PlayerId, position.VehicleId)
+	receivers := []*tcp.TCPConnection{}
+
+	for _, receiver := range s.tcp.Connections.Snapshot() {
+		if receiver == session || receiver.GetState() != types.StatePlaying {
+			continue
+		}
+
+		var distance float64
+		var ready bool
+
+		receiver.ViewPlayer(func(p *types.Player) {
+			ready = p.UDPAddress != nil
+			distance = nearestVehicle(p, position.Position)
+		})
+
+		if !ready || !s.relay.Due(receiver, vehicle, RelayInterval(distance), now) {
+			continue
+		}
+
+		receivers = append(receivers, receiver)
+	}
+
+	if len(receivers) == 0 {
+		return
+	}
+
+	if err := s.Broadcast(receivers, packet.Data); err != nil {
+		s.Debugf("Unable to relay position for vehicle %s - %s", vehicle, err.Error())
+	}
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,81 @@
+package udp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/tcp"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+func TestRelayInterval(t *testing.T) {
+	config.Configuration.NetBeams.RelayNearDistance = 300
+	config.Configuration.NetBeams.RelayFarDistance = 1000
+	config.Configuration.NetBeams.RelayMidIntervalTime = 100 * time.Millisecond
+	config.Configuration.NetBeams.RelayFarIntervalTime = time.Second
+
+	tests := []struct {
+		distance float64
+		want     time.Duration
+	}{
+		{0, 0},
+		{299, 0},
+		{300, 100 * time.Millisecond},
+		{999, 100 * time.Millisecond},
+		{1000, time.Second},
+		{50000, time.Second},
+	}
+
+	for _, test := range tests {
+		if got := RelayInterval(test.distance); got != test.want {
+			t.Errorf("%.0fm: expected %s, got %s", test.distance, test.want, got)
+		}
+	}
+}
+
+func TestRelayDue(t *testing.T) {
+	r := NewRelay()
+	receiver := &tcp.TCPConnection{}
+	now := time.Unix(1000, 0)
+
+	if !r.Due(receiver, "1-0", time.Second, now) {
+		t.Error("Expected the first update to be due")
+	}
+
+	if r.Due(receiver, "1-0", time.Second, now.Add(500*time.Millisecond)) {
+		t.Error("Expected an update within the interval not to be due")
+	}
+
+	if !r.Due(receiver, "1-1", time.Second, now.Add(500*time.Millisecond)) {
+		t.Error("Expected updates to be tracked per vehicle")
+	}
+
+	if !r.Due(receiver, "1-0", time.Second, now.Add(time.Second)) {
+		t.Error("Expected an update after the interval to be due")
+	}
+
+	r.Forget(receiver)
+
+	if !r.Due(receiver, "1-0", time.Second, now.Add(time.Second)) {
+		t.Error("Expected a forgotten receiver to start again")
+	}
+}
+
+func TestNearestVehicle(t *testing.T) {
+	player := &types.Player{
+		Vehicles: []*types.Vehicle{
+			{Id: 0},
+			{Id: 1, Position: [3]float64{100, 0, 0}, MovedAt: time.Now()},
+			{Id: 2, Position: [3]float64{0, 30, 40}, MovedAt: time.Now()},
+		},
+	}
+
+	if d := nearestVehicle(player, [3]float64{0, 0, 0}); d != 50 {
+		t.Errorf("Expected the nearest vehicle to be 50m away, got %.1fm", d)
+	}
+
+	if d := nearestVehicle(&types.Player{}, [3]float64{500, 0, 0}); d != 0 {
+		t.Errorf("Expected a player without vehicles to be treated as nearby, got %.1fm", d)
+	}
+}
@@ -1,20 +1,33 @@
 package udp
 
 import (
+	"fmt"
 	"net"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/tcp"
 	"github.com/altriusrs/netbeams/src/types"
 )
 
+// A Handler processes a single UDP message from the player a TCP session belongs to
+type Handler func(session *tcp.TCPConnection, packet *Packet)
+
 // A UDP server instance for the udp portion of the protocol
 type Server struct {
 	types.Service
 	Addr     string       // The address to listen on
 	Port     int          // The port to listen on
 	Listener *net.UDPConn // The UDP listener instance
+
+	transport transport        // Reads and writes datagrams on the listener
+	tcp       *tcp.Server      // The TCP server which UDP traffic is bound to
+	relay     *Relay           // Tracks which vehicle positions have been sent to each player
+	pinger    *Pinger          // Tracks the pings sent to each player
+	mutex     sync.RWMutex     // Guards the handlers
+	handlers  map[byte]Handler // Handlers keyed by message code
 }
 
 // Create a new UDP server instance
@@ -24,6 +37,9 @@ func Service() *Server {
 		Addr:     "0.0.0.0",
 		Port:     config.Configuration.General.Port,
 		Listener: nil,
+		handlers: make(map[byte]Handler),
+		relay:    NewRelay(),
+		pinger:   NewPinger(),
 	}
 
 	server.RegisterServiceHooks(server.Start, server.Shutdown, nil)
@@ -48,6 +64,18 @@ func (s *Server) Start() (types.Status, error) {
 	s.Info("Starting UDP server")
 	s.SetStatus(types.StatusStarting)
 
+	server, ok := types.App.GetService("TCP Server").(*tcp.Server)
+
+	if !ok {
+		return types.StatusErrored, fmt.Errorf("the UDP server requires the TCP server")
+	}
+
+	s.tcp = server
+	s.tcp.Connections.OnRemove(s.relay.Forget)
+	s.tcp.Connections.OnRemove(s.pinger.Forget)
+	s.Register('Z', s.HandlePosition)
+	s.Register('p', s.HandlePing)
+
 	udpAddr, err := net.ResolveUDPAddr("udp4", s.Addr+":"+strconv.Itoa(s.Port))
 
 	if err != nil {
@@ -63,8 +91,10 @@ func (s *Server) Start() (types.Status, error) {
 	}
 
 	s.Listener = listener
+	s.transport = newTransport(listener)
 
 	go s.Listen()
+	go s.PingLoop()
 
 	return types.StatusHealthy, nil
 }
@@ -72,9 +102,22 @@ func (s *Server) Start() (types.Status, error) {
 func (s *Server) Listen() {
 	s.SetStatus(types.StatusHealthy)
 
+	// The receive buffers are reused for every batch, as packets are handled before the next read
+	packets := make([]*Packet, BatchSize)
+
+	for i := range packets {
+		packets[i] = AcquirePacket()
+	}
+
+	defer func() {
+		for _, p := range packets {
+			p.Release()
+		}
+	}()
+
 	// While the server is healthy, listen for incoming UDP packets
 	for *s.Status == types.StatusHealthy {
-		packet, err := ReadPacketFromUDP(s.Listener)
+		n, err := s.transport.ReadBatch(packets)
 
 		if err != nil {
 			// If the error is a closed network connection, break out of the loop
@@ -83,15 +126,124 @@ func (s *Server) Listen() {
 				break
 			}
 			// Otherwise, continue attempting to listen to packets
-			s.Error("Error reading UDP packet: " + err.Error())
+			s.Debugf("Unable to read UDP packets - %s", err.Error())
 			continue
 		}
 
-		s.Info("Received UDP packet from " + packet.Source.String())
-		s.Infof("Packet: %s", packet.Data)
-
-		// types.App.GetService("Player Manager").HandlePacket(packet)
+		for _, packet := range packets[:n] {
+			s.Handle(packet)
+		}
 	}
 
 	s.SetStatus(types.StatusStopped)
 }
+
+// Handle a datagram which has been read into a packet
+func (s *Server) Handle(packet *Packet) {
+	if err := packet.Decode(); err != nil {
+		s.Debugf("Dropped UDP packet from %s - %s", packet.Source, err.Error())
+		return
+	}
+
+	session := s.Bind(packet)
+
+	if session == nil {
+		return
+	}
+
+	s.Dispatch(session, packet)
+}
+
+// Bind matches a packet to the TCP session of the player it claims to be from.
+// Packets which do not come from the same IP address as the session are dropped.
+func (s *Server) Bind(packet *Packet) *tcp.TCPConnection {
+	session := s.tcp.Connections.GetByPlayerId(packet.PlayerId)
+
+	if session == nil {
+		s.Debugf("Dropped UDP packet from %s - No session for player %d", packet.Source, packet.PlayerId)
+		return nil
+	}
+
+	tcpAddr, ok := session.Conn.RemoteAddr().(*net.TCPAddr)
+
+	if !ok || tcpAddr.AddrPort().Addr().Unmap() != packet.Source.Addr() {
+		s.Warnf("Dropped UDP packet from %s - Address does not match the session of player %d", packet.Source, packet.PlayerId)
+		return nil
+	}
+
+	player := session.GetPlayer()
+
+	if player.UDPAddress == nil || addrPort(player.UDPAddress) != packet.Source {
+		session.UpdatePlayer(func(p *types.Player) {
+			p.UDPAddress = net.UDPAddrFromAddrPort(packet.Source)
+		})
+
+		session.Infof("Bound UDP address %s", packet.Source)
+	}
+
+	return session
+}
+
+// Register a handler for a message code, replacing any handler already registered for it
+func (s *Server) Register(code byte, handler Handler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.handlers[code] = handler
+}
+
+// Remove the handler for a message code
+func (s *Server) Unregister(code byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.handlers, code)
+}
+
+// Dispatch a packet to the handler registered for its code
+func (s *Server) Dispatch(session *tcp.TCPConnection, packet *Packet) {
+	s.mutex.RLock()
+	handler, ok := s.handlers[packet.Code()]
+	s.mutex.RUnlock()
+
+	if !ok {
+		s.Debugf("Ignoring UDP message with code %q from player %d", packet.Code(), packet.PlayerId)
+		return
+	}
+
+	handler(session, packet)
+}
+
+// Send data to the player a TCP session belongs to, once their UDP address is known
+func (s *Server) Send(session *tcp.TCPConnection, data []byte) error {
+	addr := session.GetPlayer().UDPAddress
+
+	if addr == nil {
+		return fmt.Errorf("the UDP address of %s is not known yet", session.Address)
+	}
+
+	packet := Packet{Data: data}
+
+	_, err := s.Listener.WriteToUDPAddrPort(packet.Serialize(), addrPort(addr))
+
+	return err
+}
+
+// Broadcast the same data to the players many TCP sessions belong to, using as few system calls as possible.
+// Sessions whose UDP address is not known yet are skipped.
+func (s *Server) Broadcast(sessions []*tcp.TCPConnection, data []byte) error {
+	packet := Packet{Data: data}
+	payload := packet.Serialize()
+
+	datagrams := make([]Datagram, 0, len(sessions))
+
+	for _, session := range sessions {
+		if addr := session.GetPlayer().UDPAddress; addr != nil {
+			datagrams = append(datagrams, Datagram{Addr: addrPort(addr), Data: payload})
+		}
+	}
+
+	_, err := s.transport.WriteBatch(datagrams)
+
+	return err
+}
@@ -0,0 +1,125 @@
+package udp
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/altriusrs/netbeams/src/player_manager"
+	"github.com/altriusrs/netbeams/src/tcp"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// A connection which reports the given remote address, as the TCP peer of a session
+type peerConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c *peerConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// Create a UDP server bound to a TCP server which is not listening
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+
+	types.NewApplication()
+	types.App.AddService(player_manager.Service())
+
+	s := Service()
+	s.tcp = tcp.Service()
+
+	return s
+}
+
+// Add a TCP session for a player, connected from the given address
+func addTestSession(t *testing.T, s *Server, id int, peer string) *tcp.TCPConnection {
+	t.Helper()
+
+	server, client := net.Pipe()
+
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+
+	remote := net.TCPAddrFromAddrPort(netip.MustParseAddrPort(peer))
+	session := tcp.NewTCPConnection(&peerConn{Conn: server, remote: remote}, fmt.Sprintf("session-%d", id), s.tcp)
+	session.SetPlayer(types.Player{PlayerId: id, Account: &types.Account{Id: fmt.Sprint(id)}})
+	s.tcp.Connections.Add(session)
+
+	return session
+}
+
+// Create a decoded packet claiming to be from a player, sent from the given address
+func newTestPacket(t *testing.T, id int, source string) *Packet {
+	t.Helper()
+
+	packet := AcquirePacket()
+	t.Cleanup(packet.Release)
+
+	// The client sends its player ID offset by one, followed by a separator
+	n := copy(packet.buf, []byte{byte(id + 1), ':', 'p'})
+	packet.received(n, netip.MustParseAddrPort(source), false)
+
+	if err := packet.Decode(); err != nil {
+		t.Fatal(err)
+	}
+
+	return packet
+}
+
+func TestBind(t *testing.T) {
+	s := newTestServer(t)
+
+	sessions := map[int]*tcp.TCPConnection{
+		1: addTestSession(t, s, 1, "198.51.100.7:40000"),
+		2: addTestSession(t, s, 2, "[::ffff:198.51.100.9]:40000"),
+	}
+
+	// The cases run in order, as each binding is kept for the cases after it
+	tests := []struct {
+		name   string
+		id     int
+		source string
+		bound  bool
+		udp    string // The UDP address of the player afterwards, or empty if it is not known
+	}{
+		{"no session for the player", 3, "198.51.100.7:5000", false, ""},
+		{"address differs from the TCP peer", 1, "198.51.100.8:5000", false, ""},
+		{"IPv4-mapped source", 1, "[::ffff:198.51.100.7]:5000", true, "198.51.100.7:5000"},
+		{"same source", 1, "198.51.100.7:5000", true, "198.51.100.7:5000"},
+		{"source port changes", 1, "198.51.100.7:5001", true, "198.51.100.7:5001"},
+		{"IPv4-mapped TCP peer", 2, "198.51.100.9:5000", true, "198.51.100.9:5000"},
+		{"address differs from an IPv4-mapped TCP peer", 2, "198.51.100.7:5000", false, "198.51.100.9:5000"},
+	}
+
+	for _, test := range tests {
+		session := s.Bind(newTestPacket(t, test.id, test.source))
+
+		if bound := session != nil; bound != test.bound {
+			t.Errorf("%s: expected bound to be %t, got %t", test.name, test.bound, bound)
+		}
+
+		if session != nil && session != sessions[test.id] {
+			t.Errorf("%s: expected the session of player %d", test.name, test.id)
+		}
+
+		expected, ok := sessions[test.id]
+
+		if !ok {
+			continue
+		}
+
+		udp := ""
+		if addr := expected.GetPlayer().UDPAddress; addr != nil {
+			udp = addr.String()
+		}
+
+		if udp != test.udp {
+			t.Errorf("%s: expected the UDP address of player %d to be %q, got %q", test.name, test.id, test.udp, udp)
+		}
+	}
+}
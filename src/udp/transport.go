@@ -0,0 +1,57 @@
+package udp
+
+import (
+	"net"
+	"net/netip"
+)
+
+// The number of datagrams read or written with a single system call, where the platform supports batching
+const BatchSize = 32
+
+// A Datagram waiting to be sent
+type Datagram struct {
+	Addr netip.AddrPort // The address to send the datagram to
+	Data []byte         // The serialized datagram
+}
+
+// A transport moves datagrams in and out of the UDP socket, in batches where the platform allows it
+type transport interface {
+	// Read at least one datagram into the packets, returning the number read
+	ReadBatch(packets []*Packet) (int, error)
+
+	// Write the datagrams, returning the number written
+	WriteBatch(datagrams []Datagram) (int, error)
+}
+
+// The portable transport, which uses a system call for each datagram
+type basicTransport struct {
+	conn *net.UDPConn
+}
+
+func (t *basicTransport) ReadBatch(packets []*Packet) (int, error) {
+	n, addr, err := t.conn.ReadFromUDPAddrPort(packets[0].buf)
+
+	if err != nil {
+		return 0, err
+	}
+
+	packets[0].received(n, addr, false)
+
+	return 1, nil
+}
+
+func (t *basicTransport) WriteBatch(datagrams []Datagram) (int, error) {
+	for i, d := range datagrams {
+		if _, err := t.conn.WriteToUDPAddrPort(d.Data, d.Addr); err != nil {
+			return i, err
+		}
+	}
+
+	return len(datagrams), nil
+}
+
+// Get the address of a UDP peer, with IPv4 addresses in their 4 byte form
+func addrPort(addr *net.UDPAddr) netip.AddrPort {
+	ap := addr.AddrPort()
+	return netip.AddrPortFrom(ap.Addr().Unmap(), ap.Port())
+}
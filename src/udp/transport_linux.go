@@ -0,0 +1,204 @@
+//go:build linux
+
+package udp
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// The mmsghdr structure used by recvmmsg and sendmmsg
+type mmsghdr struct {
+	Hdr unix.Msghdr
+	Len uint32
+}
+
+// The message headers passed to a single recvmmsg or sendmmsg call, reused between calls
+type batchScratch struct {
+	hdrs  []mmsghdr
+	iovs  []unix.Iovec
+	names []unix.RawSockaddrInet6 // Large enough for both IPv4 and IPv6 addresses
+}
+
+func newBatchScratch(size int) batchScratch {
+	return batchScratch{
+		hdrs:  make([]mmsghdr, size),
+		iovs:  make([]unix.Iovec, size),
+		names: make([]unix.RawSockaddrInet6, size),
+	}
+}
+
+// Point a message header at a buffer and address
+func (s *batchScratch) set(i int, buf []byte, namelen uint32) {
+	if len(buf) > 0 {
+		s.iovs[i].Base = &buf[0]
+	} else {
+		s.iovs[i].Base = nil
+	}
+	s.iovs[i].SetLen(len(buf))
+
+	s.hdrs[i] = mmsghdr{}
+	s.hdrs[i].Hdr.Name = (*byte)(unsafe.Pointer(&s.names[i]))
+	s.hdrs[i].Hdr.Namelen = namelen
+	s.hdrs[i].Hdr.Iov = &s.iovs[i]
+	s.hdrs[i].Hdr.SetIovlen(1)
+}
+
+// The Linux transport, which reads and writes many datagrams at once with recvmmsg and sendmmsg
+type batchTransport struct {
+	raw    syscall.RawConn
+	family int // The address family of the socket (a dual stack socket is AF_INET6)
+
+	read       batchScratch // Only used by the listener goroutine
+	writeMutex sync.Mutex   // Guards the write scratch, as packets are sent from many goroutines
+	write      batchScratch
+}
+
+// Use batched system calls, falling back to the portable transport if the socket cannot be accessed
+func newTransport(conn *net.UDPConn) transport {
+	raw, err := conn.SyscallConn()
+
+	if err != nil {
+		return &basicTransport{conn: conn}
+	}
+
+	family := 0
+	var sockErr error
+
+	err = raw.Control(func(fd uintptr) {
+		family, sockErr = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_DOMAIN)
+	})
+
+	if err != nil || sockErr != nil {
+		return &basicTransport{conn: conn}
+	}
+
+	return &batchTransport{
+		raw:    raw,
+		family: family,
+		read:   newBatchScratch(BatchSize),
+		write:  newBatchScratch(BatchSize),
+	}
+}
+
+func (t *batchTransport) ReadBatch(packets []*Packet) (int, error) {
+	n := minInt(len(packets), BatchSize)
+
+	for i := 0; i < n; i++ {
+		t.read.set(i, packets[i].buf, unix.SizeofSockaddrInet6)
+	}
+
+	var count int
+	var errno syscall.Errno
+
+	err := t.raw.Read(func(fd uintptr) bool {
+		r, _, e := unix.Syscall6(unix.SYS_RECVMMSG, fd, uintptr(unsafe.Pointer(&t.read.hdrs[0])), uintptr(n), 0, 0, 0)
+
+		// Wait for the socket to become readable
+		if e == unix.EAGAIN {
+			return false
+		}
+
+		count, errno = int(r), e
+		return true
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	if errno != 0 {
+		return 0, os.NewSyscallError("recvmmsg", errno)
+	}
+
+	for i := 0; i < count; i++ {
+		h := &t.read.hdrs[i]
+		packets[i].received(int(h.Len), readSockaddr(&t.read.names[i]), h.Hdr.Flags&unix.MSG_TRUNC != 0)
+	}
+
+	return count, nil
+}
+
+func (t *batchTransport) WriteBatch(datagrams []Datagram) (int, error) {
+	t.writeMutex.Lock()
+	defer t.writeMutex.Unlock()
+
+	sent := 0
+
+	for sent < len(datagrams) {
+		chunk := datagrams[sent:]
+
+		if len(chunk) > BatchSize {
+			chunk = chunk[:BatchSize]
+		}
+
+		for i, d := range chunk {
+			t.write.set(i, d.Data, writeSockaddr(&t.write.names[i], d.Addr, t.family))
+		}
+
+		var count int
+		var errno syscall.Errno
+
+		err := t.raw.Write(func(fd uintptr) bool {
+			r, _, e := unix.Syscall6(unix.SYS_SENDMMSG, fd, uintptr(unsafe.Pointer(&t.write.hdrs[0])), uintptr(len(chunk)), 0, 0, 0)
+
+			// Wait for the socket to become writable
+			if e == unix.EAGAIN {
+				return false
+			}
+
+			count, errno = int(r), e
+			return true
+		})
+
+		if err != nil {
+			return sent, err
+		}
+
+		if errno != 0 {
+			return sent, os.NewSyscallError("sendmmsg", errno)
+		}
+
+		sent += count
+	}
+
+	return sent, nil
+}
+
+// Convert a socket address written by the kernel to an address and port
+func readSockaddr(sa *unix.RawSockaddrInet6) netip.AddrPort {
+	switch sa.Family {
+	case unix.AF_INET:
+		sa4 := (*unix.RawSockaddrInet4)(unsafe.Pointer(sa))
+		port := (*[2]byte)(unsafe.Pointer(&sa4.Port))
+		return netip.AddrPortFrom(netip.AddrFrom4(sa4.Addr), uint16(port[0])<<8|uint16(port[1]))
+	case unix.AF_INET6:
+		port := (*[2]byte)(unsafe.Pointer(&sa.Port))
+		return netip.AddrPortFrom(netip.AddrFrom16(sa.Addr), uint16(port[0])<<8|uint16(port[1]))
+	default:
+		return netip.AddrPort{}
+	}
+}
+
+// Write an address and port as a socket address for the family of the socket, returning its length
+func writeSockaddr(sa *unix.RawSockaddrInet6, addr netip.AddrPort, family int) uint32 {
+	if family == unix.AF_INET && addr.Addr().Is4() {
+		sa4 := (*unix.RawSockaddrInet4)(unsafe.Pointer(sa))
+		*sa4 = unix.RawSockaddrInet4{Family: unix.AF_INET, Addr: addr.Addr().As4()}
+		port := (*[2]byte)(unsafe.Pointer(&sa4.Port))
+		port[0], port[1] = byte(addr.Port()>>8), byte(addr.Port())
+		return unix.SizeofSockaddrInet4
+	}
+
+	// IPv4 addresses are sent from a dual stack socket in their mapped form
+	*sa = unix.RawSockaddrInet6{Family: unix.AF_INET6, Addr: addr.Addr().As16()}
+	port := (*[2]byte)(unsafe.Pointer(&sa.Port))
+	port[0], port[1] = byte(addr.Port()>>8), byte(addr.Port())
+	return unix.SizeofSockaddrInet6
+}
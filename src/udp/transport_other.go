@@ -0,0 +1,10 @@
+//go:build !linux
+
+package udp
+
+import "net"
+
+// Batched system calls are only implemented on Linux
+func newTransport(conn *net.UDPConn) transport {
+	return &basicTransport{conn: conn}
+}
@@ -0,0 +1,305 @@
+package udp
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// Open a UDP socket on the loopback interface
+func listenLoopback(t testing.TB) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+
+	if err != nil {
+		t.Fatalf("Unable to listen on the loopback interface: %s", err.Error())
+	}
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return conn
+}
+
+// Build a datagram as the client sends it
+func datagram(playerId int, payload []byte) []byte {
+	return append([]byte{byte(playerId + 1), ':'}, payload...)
+}
+
+func TestReadPacketFromUDPSizing(t *testing.T) {
+	server := listenLoopback(t)
+	client := listenLoopback(t)
+
+	// Larger than the old fixed buffer, so it would previously have been truncated
+	payload := bytes.Repeat([]byte("z"), 3000)
+
+	if _, err := client.WriteToUDP(datagram(4, payload), server.LocalAddr().(*net.UDPAddr)); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	packet, err := ReadPacketFromUDP(server)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer packet.Release()
+
+	if packet.PlayerId != 4 {
+		t.Errorf("Expected player 4, got %d", packet.PlayerId)
+	}
+
+	if !bytes.Equal(packet.Data, payload) {
+		t.Errorf("Expected %d bytes of data, got %d", len(payload), len(packet.Data))
+	}
+
+	if packet.Source != client.LocalAddr().(*net.UDPAddr).AddrPort() {
+		t.Errorf("Expected the source to be %s, got %s", client.LocalAddr(), packet.Source)
+	}
+}
+
+func TestReadPacketFromUDPInvalid(t *testing.T) {
+	server := listenLoopback(t)
+	client := listenLoopback(t)
+
+	// Too short to contain a player ID
+	if _, err := client.WriteToUDP([]byte{1}, server.LocalAddr().(*net.UDPAddr)); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	packet, err := ReadPacketFromUDP(server)
+
+	if err != ErrShortPacket {
+		t.Errorf("Expected ErrShortPacket, got %v", err)
+	}
+
+	// The packet is released to the pool, so none is returned to the caller
+	if packet != nil {
+		t.Errorf("Expected no packet to be returned, got %+v", packet)
+	}
+}
+
+func TestPacketDecodeShort(t *testing.T) {
+	packet := AcquirePacket()
+	defer packet.Release()
+
+	packet.received(1, netip.AddrPort{}, false)
+
+	if err := packet.Decode(); err != ErrShortPacket {
+		t.Errorf("Expected ErrShortPacket, got %v", err)
+	}
+
+	packet.received(10, netip.AddrPort{}, true)
+
+	if err := packet.Decode(); err != ErrTruncatedPacket {
+		t.Errorf("Expected ErrTruncatedPacket, got %v", err)
+	}
+}
+
+func TestTransportReadBatch(t *testing.T) {
+	server := listenLoopback(t)
+	client := listenLoopback(t)
+	transport := newTransport(server)
+
+	sizes := []int{1, 10, 1200, 9000}
+
+	for i, size := range sizes {
+		if _, err := client.WriteToUDP(datagram(i, bytes.Repeat([]byte("x"), size)), server.LocalAddr().(*net.UDPAddr)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	packets := make([]*Packet, BatchSize)
+
+	for i := range packets {
+		packets[i] = AcquirePacket()
+		defer packets[i].Release()
+	}
+
+	_ = server.SetReadDeadline(time.Now().Add(5 * time.Second))
+	received := 0
+
+	for received < len(sizes) {
+		n, err := transport.ReadBatch(packets)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, packet := range packets[:n] {
+			if err := packet.Decode(); err != nil {
+				t.Fatal(err)
+			}
+
+			if packet.PlayerId != received || len(packet.Data) != sizes[received] {
+				t.Errorf("Expected %d bytes from player %d, got %d bytes from player %d", sizes[received], received, len(packet.Data), packet.PlayerId)
+			}
+
+			received++
+		}
+	}
+}
+
+func TestTransportWriteBatch(t *testing.T) {
+	server := listenLoopback(t)
+	transport := newTransport(server)
+
+	receivers := []*net.UDPConn{listenLoopback(t), listenLoopback(t)}
+	datagrams := []Datagram{}
+
+	for i, r := range receivers {
+		datagrams = append(datagrams, Datagram{
+			Addr: r.LocalAddr().(*net.UDPAddr).AddrPort(),
+			Data: []byte{'p', byte(i)},
+		})
+	}
+
+	if n, err := transport.WriteBatch(datagrams); err != nil || n != len(datagrams) {
+		t.Fatalf("Expected %d datagrams to be sent, got %d (%v)", len(datagrams), n, err)
+	}
+
+	for i, r := range receivers {
+		buf := make([]byte, 16)
+
+		_ = r.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, err := r.Read(buf)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(buf[:n], []byte{'p', byte(i)}) {
+			t.Errorf("Receiver %d got %q", i, buf[:n])
+		}
+	}
+}
+
+// Position updates are the bulk of the UDP traffic, and are usually a few hundred bytes
+var benchmarkPayload = datagram(0, append([]byte("Zp:0-0:"), bytes.Repeat([]byte("0"), 300)...))
+
+func BenchmarkPacketDecode(b *testing.B) {
+	packet := AcquirePacket()
+	defer packet.Release()
+
+	n := copy(packet.buf, benchmarkPayload)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		packet.received(n, netip.AddrPort{}, false)
+
+		if err := packet.Decode(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Measure the receive rate of a transport, by filling the socket buffer with position updates and draining it.
+// Run with -cpu 1 to find the packets per second each core can receive.
+func benchmarkRead(b *testing.B, transport func(conn *net.UDPConn) transport) {
+	server := listenLoopback(b)
+	client := listenLoopback(b)
+	reader := transport(server)
+
+	// Leave room for a full round of datagrams, so that none are dropped
+	const round = 4 * BatchSize
+	_ = server.SetReadBuffer(4 << 20)
+
+	packets := make([]*Packet, BatchSize)
+
+	for i := range packets {
+		packets[i] = AcquirePacket()
+		defer packets[i].Release()
+	}
+
+	_ = server.SetReadDeadline(time.Now().Add(time.Minute))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	var elapsed time.Duration
+
+	for received := 0; received < b.N; {
+		pending := minInt(round, b.N-received)
+
+		b.StopTimer()
+		for i := 0; i < pending; i++ {
+			if _, err := client.WriteToUDP(benchmarkPayload, server.LocalAddr().(*net.UDPAddr)); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.StartTimer()
+
+		start := time.Now()
+
+		for pending > 0 {
+			n, err := reader.ReadBatch(packets[:minInt(pending, BatchSize)])
+
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			for _, packet := range packets[:n] {
+				if err := packet.Decode(); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			pending -= n
+			received += n
+		}
+
+		elapsed += time.Since(start)
+	}
+
+	b.ReportMetric(float64(b.N)/elapsed.Seconds(), "packets/s")
+}
+
+func BenchmarkReadSingle(b *testing.B) {
+	benchmarkRead(b, func(conn *net.UDPConn) transport {
+		return &basicTransport{conn: conn}
+	})
+}
+
+func BenchmarkReadBatch(b *testing.B) {
+	benchmarkRead(b, newTransport)
+}
+
+// Measure the send rate of a transport when relaying a position update to a full server
+func benchmarkWrite(b *testing.B, transport func(conn *net.UDPConn) transport) {
+	server := listenLoopback(b)
+	sink := listenLoopback(b)
+	writer := transport(server)
+
+	datagrams := make([]Datagram, 16)
+
+	for i := range datagrams {
+		datagrams[i] = Datagram{Addr: sink.LocalAddr().(*net.UDPAddr).AddrPort(), Data: benchmarkPayload}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := time.Now()
+
+	for sent := 0; sent < b.N; sent += len(datagrams) {
+		if _, err := writer.WriteBatch(datagrams); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "packets/s")
+}
+
+func BenchmarkWriteSingle(b *testing.B) {
+	benchmarkWrite(b, func(conn *net.UDPConn) transport {
+		return &basicTransport{conn: conn}
+	})
+}
+
+func BenchmarkWriteBatch(b *testing.B) {
+	benchmarkWrite(b, newTransport)
+}
@@ -0,0 +1,282 @@
+package vehicles
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/protocol"
+	"github.com/altriusrs/netbeams/src/tcp"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// A Vehicle Manager service instance, which tracks the vehicles spawned by each player
+type VehicleManager struct {
+	types.Service
+	server *tcp.Server // The TCP server which vehicle changes are relayed through
+}
+
+// Create a new Vehicle Manager service instance
+func Service() *VehicleManager {
+	vm := &VehicleManager{
+		Service: types.SpinUp("Vehicle Manager"),
+	}
+
+	vm.RegisterServiceHooks(vm.Start, vm.Stop, nil)
+
+	return vm
+}
+
+func (s *VehicleManager) Start() (types.Status, error) {
+	server, ok := types.App.GetService("TCP Server").(*tcp.Server)
+
+	if !ok {
+		return types.StatusErrored, fmt.Errorf("the vehicle manager requires the TCP server")
+	}
+
+	s.server = server
+	s.server.Dispatcher.Register('O', s.HandleVehicle)
+	s.server.Connections.OnRemove(s.RemoveAll)
+
+	return types.StatusHealthy, nil
+}
+
+func (s *VehicleManager) Stop() (types.Status, error) {
+	if s.server != nil {
+		s.server.Dispatcher.Unregister('O')
+	}
+
+	return types.StatusShutdown, nil
+}
+
+// HandleVehicle routes a vehicle (O) message based on its sub code
+func (s *VehicleManager) HandleVehicle(c *tcp.TCPConnection, packet types.TcpPacket) {
+	var err error
+
+	switch protocol.VehicleCode(packet.Data) {
+	case protocol.VehicleSpawnCode:
+		err = s.spawn(c, packet)
+	case protocol.VehicleEditCode:
+		err = s.edit(c, packet)
+	case protocol.VehicleDeleteCode:
+		err = s.delete(c, packet)
+	case protocol.VehicleResetCode:
+		err = s.reset(c, packet)
+	default:
+		err = s.relay(c, packet)
+	}
+
+	if err != nil {
+		c.Warnf("Rejected vehicle message - %s", err.Error())
+	}
+}
+
+// Spawn a vehicle for the player, if they are within their vehicle limit
+func (s *VehicleManager) spawn(c *tcp.TCPConnection, packet types.TcpPacket) error {
+	var message protocol.VehicleSpawn
+
+	if err := protocol.Decode(packet, &message); err != nil {
+		return err
+	}
+
+	player := c.GetPlayer()
+
+	message.PlayerId = player.PlayerId
+	message.Name = player.DisplayName
+	message.VehicleId = nextVehicleId(player.Vehicles)
+
+	if player.Account != nil {
+		message.Roles = types.FormatRoles(player.Account.Roles)
+	}
+
+	limit := config.MaxCarsFor(player.Account)
+
+	if limit >= 0 && len(player.Vehicles) >= limit && !player.Permissions.BypassVehicles {
+		// The client has already spawned the vehicle locally, so it has to be told to remove it again
+		c.Write(protocol.Packet(&message))
+		c.Write(protocol.Packet(&protocol.VehicleDelete{PlayerId: message.PlayerId, VehicleId: message.VehicleId}))
+		return fmt.Errorf("%s has reached the vehicle limit (%d)", player.DisplayName, limit)
+	}
+
+	vehicle := &types.Vehicle{
+		Id:        message.VehicleId,
+		OwnerId:   message.PlayerId,
+		Config:    message.Config,
+		UpdatedAt: time.Now(),
+	}
+
+	c.UpdatePlayer(func(p *types.Player) {
+		p.Vehicles = append(p.Vehicles, vehicle)
+	})
+
+	c.Debugf("Spawned vehicle %s", vehicle)
+
+	// The owner needs the packet as well, as it carries the ID assigned to the vehicle
+	s.server.Connections.Broadcast(protocol.Packet(&message), nil)
+
+	return nil
+}
+
+// Update the configuration of one of the player's vehicles
+func (s *VehicleManager) edit(c *tcp.TCPConnection, packet types.TcpPacket) error {
+	var message protocol.VehicleEdit
+
+	if err := protocol.Decode(packet, &message); err != nil {
+		return err
+	}
+
+	err := s.update(c, message.PlayerId, message.VehicleId, func(v *types.Vehicle) {
+		v.Config = message.Config
+	})
+
+	if err != nil {
+		return err
+	}
+
+	s.server.Connections.Broadcast(packet, c)
+
+	return nil
+}
+
+// Remove one of the player's vehicles
+func (s *VehicleManager) delete(c *tcp.TCPConnection, packet types.TcpPacket) error {
+	var message protocol.VehicleDelete
+
+	if err := protocol.Decode(packet, &message); err != nil {
+		return err
+	}
+
+	removed := false
+
+	c.UpdatePlayer(func(p *types.Player) {
+		if p.PlayerId != message.PlayerId {
+			return
+		}
+
+		for i, v := range p.Vehicles {
+			if v.Id == message.VehicleId {
+				p.Vehicles = append(p.Vehicles[:i:i], p.Vehicles[i+1:]...)
+				removed = true
+				return
+			}
+		}
+	})
+
+	if !removed {
+		return fmt.Errorf("vehicle %s does not belong to the player", protocol.FormatVehicleId(message.PlayerId, message.VehicleId))
+	}
+
+	c.Debugf("Deleted vehicle %s", protocol.FormatVehicleId(message.PlayerId, message.VehicleId))
+
+	s.server.Connections.Broadcast(packet, nil)
+
+	return nil
+}
+
+// Record the reset state of one of the player's vehicles
+func (s *VehicleManager) reset(c *tcp.TCPConnection, packet types.TcpPacket) error {
+	var message protocol.VehicleReset
+
+	if err := protocol.Decode(packet, &message); err != nil {
+		return err
+	}
+
+	err := s.update(c, message.PlayerId, message.VehicleId, func(v *types.Vehicle) {
+		v.State = message.Data
+	})
+
+	if err != nil {
+		return err
+	}
+
+	s.server.Connections.Broadcast(packet, c)
+
+	return nil
+}
+
+// Relay any other vehicle message to the other players, provided the sender owns the vehicle
+func (s *VehicleManager) relay(c *tcp.TCPConnection, packet types.TcpPacket) error {
+	text := packet.String()
+
+	if len(text) < 4 || text[2] != ':' {
+		return fmt.Errorf("malformed vehicle message")
+	}
+
+	id := strings.SplitN(text[3:], ":", 2)[0]
+	playerId, vehicleId, err := protocol.ParseVehicleId(id)
+
+	if err != nil {
+		return err
+	}
+
+	if err = s.update(c, playerId, vehicleId, func(v *types.Vehicle) {}); err != nil {
+		return err
+	}
+
+	s.server.Connections.Broadcast(packet, c)
+
+	return nil
+}
+
+// Apply a change to one of the player's vehicles, failing if the player does not own it
+func (s *VehicleManager) update(c *tcp.TCPConnection, playerId int, vehicleId int, change func(v *types.Vehicle)) error {
+	found := false
+
+	c.UpdatePlayer(func(p *types.Player) {
+		if p.PlayerId != playerId {
+			return
+		}
+
+		for _, v := range p.Vehicles {
+			if v.Id == vehicleId {
+				change(v)
+				v.UpdatedAt = time.Now()
+				found = true
+				return
+			}
+		}
+	})
+
+	if !found {
+		return fmt.Errorf("vehicle %s does not belong to the player", protocol.FormatVehicleId(playerId, vehicleId))
+	}
+
+	return nil
+}
+
+// RemoveAll removes every vehicle owned by a connection, telling the other players to delete them
+func (s *VehicleManager) RemoveAll(c *tcp.TCPConnection) {
+	var vehicles []*types.Vehicle
+
+	c.UpdatePlayer(func(p *types.Player) {
+		vehicles = p.Vehicles
+		p.Vehicles = nil
+	})
+
+	for _, v := range vehicles {
+		s.server.Connections.Broadcast(protocol.Packet(&protocol.VehicleDelete{PlayerId: v.OwnerId, VehicleId: v.Id}), c)
+	}
+
+	if len(vehicles) > 0 {
+		s.Debugf("Removed %d vehicles owned by %s", len(vehicles), c.Address)
+	}
+}
+
+// Get the lowest vehicle ID which is not in use by the player
+func nextVehicleId(vehicles []*types.Vehicle) int {
+	for id := 0; ; id++ {
+		taken := false
+
+		for _, v := range vehicles {
+			if v.Id == id {
+				taken = true
+				break
+			}
+		}
+
+		if !taken {
+			return id
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package vehicles
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/altriusrs/netbeams/src/config"
+	"github.com/altriusrs/netbeams/src/tcp/tcptest"
+	"github.com/altriusrs/netbeams/src/types"
+)
+
+// Create a vehicle manager with a TCP server which is not listening
+func newTestManager(t *testing.T) *VehicleManager {
+	t.Helper()
+
+	s := Service()
+	s.server = tcptest.NewServer(t)
+
+	return s
+}
+
+func TestSpawnRejectedOverVehicleLimit(t *testing.T) {
+	s := newTestManager(t)
+	config.Configuration.General.MaxCars = 1
+	config.Configuration.Auth.Roles = nil
+
+	alice := tcptest.NewPlayer(t, s.server, 0, "Alice")
+	bob := tcptest.NewPlayer(t, s.server, 1, "Bob")
+
+	s.HandleVehicle(alice.Connection, types.NewTcpPacket(`Os:0:{"jbm":"pickup"}`))
+
+	alice.Expect(t, `Os:USER:Alice:0-0:{"jbm":"pickup"}`)
+	bob.Expect(t, `Os:USER:Alice:0-0:{"jbm":"pickup"}`)
+
+	// The second vehicle is over the limit, so the client is told to delete the vehicle it spawned locally
+	s.HandleVehicle(alice.Connection, types.NewTcpPacket(`Os:0:{"jbm":"van"}`))
+
+	alice.Expect(t, `Os:USER:Alice:0-1:{"jbm":"van"}`)
+	alice.Expect(t, "Od:0-1")
+	bob.ExpectNothing(t)
+
+	if vehicles := len(alice.Connection.GetPlayer().Vehicles); vehicles != 1 {
+		t.Errorf("Expected the player to own 1 vehicle, got %d", vehicles)
+	}
+}
+
+func TestRemoveAllOnDisconnect(t *testing.T) {
+	s := newTestManager(t)
+	config.Configuration.General.MaxCars = 2
+	config.Configuration.Auth.Roles = nil
+
+	alice := tcptest.NewPlayer(t, s.server, 0, "Alice")
+	bob := tcptest.NewPlayer(t, s.server, 1, "Bob")
+
+	for i := 0; i < 2; i++ {
+		s.HandleVehicle(alice.Connection, types.NewTcpPacket(`Os:0:{}`))
+		alice.Expect(t, fmt.Sprintf("Os:USER:Alice:0-%d:{}", i))
+		bob.Expect(t, fmt.Sprintf("Os:USER:Alice:0-%d:{}", i))
+	}
+
+	s.RemoveAll(alice.Connection)
+
+	bob.Expect(t, "Od:0-0")
+	bob.Expect(t, "Od:0-1")
+	alice.ExpectNothing(t)
+
+	if vehicles := len(alice.Connection.GetPlayer().Vehicles); vehicles != 0 {
+		t.Errorf("Expected the vehicles to be removed, got %d", vehicles)
+	}
+}